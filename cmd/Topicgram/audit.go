@@ -0,0 +1,95 @@
+package main
+
+import (
+	"Topicgram/database"
+	"Topicgram/model"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+)
+
+// audit exports the audit log, usage: Topicgram audit [--bot <id>] [--user <id>] [--format csv|json]
+func audit(args []string) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	cfg := flags.String("config", "config.json", "The config file location")
+	bot_id := flags.Int64("bot", 0, "The bot id, 0 for all bots")
+	user_id := flags.Int64("user", 0, "The target user id, 0 for all users")
+	format := flags.String("format", "csv", "The output format (csv, json)")
+	output := flags.String("output", "", "The output file (default stdout)")
+	flags.Parse(args)
+
+	if *format != "csv" && *format != "json" {
+		flags.PrintDefaults()
+		return
+	}
+
+	openDatabase(*cfg)
+
+	query := database.DB().Model(model.Audit{})
+	if *bot_id != 0 {
+		query = query.Where("bot_id", *bot_id)
+	}
+	if *user_id != 0 {
+		query = query.Where("target_id", *user_id)
+	}
+
+	var audits []model.Audit
+	err := query.Order("id").Find(&audits).Error
+	if err != nil {
+		clog.Fatal("[Audit] failed to query audit log, error: ", err)
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			clog.Fatal("[Audit] failed to create output file, error: ", err)
+			return
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(audits)
+	default:
+		err = writeAuditCSV(w, audits)
+	}
+	if err != nil {
+		clog.Fatal("[Audit] failed to write audit log, error: ", err)
+		return
+	}
+
+	if *output != "" {
+		clog.Successf("[Audit] %d entries saved to %s", len(audits), *output)
+	}
+}
+
+func writeAuditCSV(w io.Writer, audits []model.Audit) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "bot_id", "actor_id", "target_id", "action", "reason", "created_at"})
+	for _, audit := range audits {
+		writer.Write([]string{
+			strconv.FormatInt(audit.Id, 10),
+			strconv.FormatInt(audit.BotId, 10),
+			strconv.FormatInt(audit.ActorId, 10),
+			strconv.FormatInt(audit.TargetId, 10),
+			string(audit.Action),
+			audit.Reason,
+			audit.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
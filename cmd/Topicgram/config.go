@@ -2,7 +2,12 @@ package main
 
 import (
 	"Topicgram/config"
+	"Topicgram/database"
 	"Topicgram/model"
+	"encoding/json"
+	"os"
+
+	"gitlab.com/CoiaPrant/clog"
 )
 
 type Config struct {
@@ -21,7 +26,28 @@ type Config struct {
 		Oracle   *config.Oracle
 	}
 
-	Bot *model.BotConfig
+	Cluster struct {
+		Enabled bool
+		NodeId  string
+	}
+
+	Dispatcher struct {
+		Workers   int
+		QueueSize int
+	}
+
+	Bots []*model.BotConfig
+	Bot  *model.BotConfig // Deprecated: use Bots
+
+	LegacyBotId int64 // the bot which owns the rows created before multi-bot support, the deprecated Bot or the only bot by default
+
+	Manager *model.ManagerConfig
+
+	API struct {
+		Token string
+	}
+
+	Events []model.EventEndpoint
 
 	Security struct {
 		InsecureSkipVerify bool
@@ -29,3 +55,48 @@ type Config struct {
 
 	Proxy string
 }
+
+func (conf *Config) database() (config.Database, bool) {
+	switch conf.Database.Type {
+	case "sqlite3":
+		return conf.Database.SQLite3, true
+	case "mysql":
+		return conf.Database.MySQL, true
+	case "postgres":
+		return conf.Database.Postgres, true
+	case "oracle":
+		return conf.Database.Oracle, true
+	default:
+		return nil, false
+	}
+}
+
+// openDatabase connects the database of the config file for the subcommands.
+func openDatabase(cfg string) {
+	var conf Config
+	{
+		file, err := os.ReadFile(cfg)
+		if err != nil {
+			clog.Fatal("[Config] Unable to read config file, error: ", err)
+			return
+		}
+
+		err = json.Unmarshal(file, &conf)
+		if err != nil {
+			clog.Fatal("[Config] Unable to parse config file, error: ", err)
+			return
+		}
+	}
+
+	dbConf, ok := conf.database()
+	if !ok || dbConf == nil {
+		clog.Fatal("[Config] Bad database config")
+		return
+	}
+
+	err := database.InitDB(dbConf)
+	if err != nil {
+		clog.Fatal("[Database] failed to connect database, error: ", err)
+		return
+	}
+}
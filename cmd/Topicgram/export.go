@@ -0,0 +1,73 @@
+package main
+
+import (
+	"Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/transcript"
+	"flag"
+	"os"
+
+	"gitlab.com/CoiaPrant/clog"
+)
+
+// export writes the transcript of a user to a file, usage: Topicgram export --user <id>
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	cfg := flags.String("config", "config.json", "The config file location")
+	user_id := flags.Int64("user", 0, "The user id")
+	bot_id := flags.Int64("bot", 0, "The bot id, required if the user talked to several bots")
+	format := flags.String("format", transcript.FormatHTML, "The transcript format (json, html, text)")
+	output := flags.String("output", "", "The output file (default transcript-<bot>-<user>.<ext>)")
+	flags.Parse(args)
+
+	if *user_id == 0 {
+		flags.PrintDefaults()
+		return
+	}
+
+	openDatabase(*cfg)
+
+	if *bot_id == 0 {
+		var bot_ids []int64
+		err := database.DB().Model(model.Archive{}).Where("user_id", *user_id).Distinct("bot_id").Pluck("bot_id", &bot_ids).Error
+		if err != nil {
+			clog.Fatal("[Export] failed to query archives, error: ", err)
+			return
+		}
+
+		switch len(bot_ids) {
+		case 0:
+			clog.Fatalf("[Export] No archived messages of user %d", *user_id)
+			return
+		case 1:
+			*bot_id = bot_ids[0]
+		default:
+			clog.Fatalf("[Export] User %d talked to several bots %v, please specify --bot", *user_id, bot_ids)
+			return
+		}
+	}
+
+	t, err := transcript.Load(*bot_id, *user_id)
+	if err != nil {
+		clog.Fatal("[Export] failed to load transcript, error: ", err)
+		return
+	}
+
+	data, err := t.Render(*format)
+	if err != nil {
+		clog.Fatal("[Export] failed to render transcript, error: ", err)
+		return
+	}
+
+	if *output == "" {
+		*output = t.FileName(*format)
+	}
+
+	err = os.WriteFile(*output, data, 0644)
+	if err != nil {
+		clog.Fatal("[Export] failed to write transcript, error: ", err)
+		return
+	}
+
+	clog.Successf("[Export] Transcript of user %d saved to %s", *user_id, *output)
+}
@@ -2,13 +2,17 @@ package main
 
 import (
 	. "Topicgram/common"
-	"Topicgram/config"
 	"Topicgram/database"
 	_ "Topicgram/i18n/languages"
+	"Topicgram/model"
 	"Topicgram/pkg/proxy"
+	"Topicgram/services/api"
 	"Topicgram/services/bots"
+	"Topicgram/services/captcha"
+	"Topicgram/services/cluster"
 	"Topicgram/services/cron"
 	_ "Topicgram/services/cron/jobs"
+	"Topicgram/services/events"
 	"Topicgram/services/webhook"
 	"context"
 	"crypto/tls"
@@ -32,6 +36,17 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			export(os.Args[2:])
+			return
+		case "audit":
+			audit(os.Args[2:])
+			return
+		}
+	}
+
 	var conf Config
 	{
 		var cfg string
@@ -98,18 +113,8 @@ func main() {
 	}
 
 	{
-		var dbConf config.Database
-
-		switch conf.Database.Type {
-		case "sqlite3":
-			dbConf = conf.Database.SQLite3
-		case "mysql":
-			dbConf = conf.Database.MySQL
-		case "postgres":
-			dbConf = conf.Database.Postgres
-		case "oracle":
-			dbConf = conf.Database.Oracle
-		default:
+		dbConf, ok := conf.database()
+		if !ok {
 			clog.Fatal("[Config] Unknown database type")
 			return
 		}
@@ -127,35 +132,167 @@ func main() {
 		clog.Success("[Database] connected database")
 	}
 
+	if conf.Cluster.Enabled {
+		cluster.Start(conf.Cluster.NodeId)
+	}
+
+	events.Load(conf.Events)
+
+	bots.StartDispatcher(conf.Dispatcher.Workers, conf.Dispatcher.QueueSize)
+
 	{
-		if conf.Bot == nil {
+		if conf.Bot != nil {
+			conf.Bots = append(conf.Bots, conf.Bot)
+		}
+
+		if len(conf.Bots) == 0 && conf.Manager == nil {
 			clog.Fatal("[Bot] Invalid config")
 			return
 		}
 
-		if conf.Bot.Token == "" {
-			clog.Fatal("[Bot] Invalid Bot Token")
+		for i, botConfig := range conf.Bots {
+			if botConfig == nil {
+				clog.Fatalf("[Bot #%d] Invalid config", i)
+				return
+			}
+
+			if botConfig.Token == "" {
+				clog.Fatalf("[Bot #%d] Invalid Bot Token", i)
+				return
+			}
+
+			if botConfig.GroupId == 0 {
+				clog.Fatalf("[Bot #%d] Invalid Bot Group Id", i)
+				return
+			}
+
+			switch botConfig.Mode {
+			case "", model.BotModeWebhook:
+				botConfig.Mode = model.BotModeWebhook
+
+				if botConfig.WebHook.Host == "" {
+					clog.Fatalf("[Bot #%d] Invalid WebHook Host", i)
+					return
+				}
+			case model.BotModePolling:
+				if conf.Cluster.Enabled {
+					clog.Fatalf("[Bot #%d] Polling mode is not supported in cluster mode", i)
+					return
+				}
+			default:
+				clog.Fatalf("[Bot #%d] Unknown Bot Mode", i)
+				return
+			}
+
+			switch botConfig.Captcha.Type {
+			case "":
+				botConfig.Captcha.Type = captcha.TypeMath
+			default:
+				if _, ok := captcha.Get(botConfig.Captcha.Type); !ok {
+					clog.Fatalf("[Bot #%d] Unknown Captcha Type", i)
+					return
+				}
+			}
+
+			switch botConfig.Captcha.Answer {
+			case "":
+				botConfig.Captcha.Answer = model.CaptchaAnswerButton
+			case model.CaptchaAnswerButton, model.CaptchaAnswerText:
+			default:
+				clog.Fatalf("[Bot #%d] Unknown Captcha Answer", i)
+				return
+			}
+
+			for j := range botConfig.Captcha.Rules {
+				if !botConfig.Captcha.Rules[j].IsValid() {
+					clog.Fatalf("[Bot #%d] Invalid Captcha Rule #%d", i, j)
+					return
+				}
+			}
+
+			err := botConfig.Schedule.Compile()
+			if err != nil {
+				clog.Fatalf("[Bot #%d] Invalid Schedule, error: %s", i, err)
+				return
+			}
+
+			for j := range botConfig.FAQ {
+				err := botConfig.FAQ[j].Compile()
+				if err != nil {
+					clog.Fatalf("[Bot #%d] Invalid FAQ Rule #%d, error: %s", i, j, err)
+					return
+				}
+			}
+
+			if botConfig.Captcha.Type == captcha.TypeWebApp {
+				if botConfig.WebHook.Host == "" {
+					clog.Fatalf("[Bot #%d] WebApp captcha requires WebHook Host", i)
+					return
+				}
+
+				if botConfig.Captcha.Answer == model.CaptchaAnswerText {
+					clog.Fatalf("[Bot #%d] WebApp captcha does not support text answer", i)
+					return
+				}
+			}
+
+			err = bots.Load(botConfig)
+			if err != nil {
+				clog.Fatalf("[Bot #%d][Initial] failed to init bot, error: %s", i, err)
+				return
+			}
+		}
+
+		legacyBotId := conf.LegacyBotId
+		if legacyBotId == 0 && conf.Bot != nil {
+			legacyBotId, _ = bots.BotIdFromToken(conf.Bot.Token)
+		}
+
+		err := bots.AdoptLegacyRows(legacyBotId)
+		if err != nil {
+			clog.Fatal("[Database] failed to migrate legacy rows, error: ", err)
+			return
+		}
+	}
+
+	if conf.Manager != nil {
+		if conf.Manager.Token == "" {
+			clog.Fatal("[Manager] Invalid Bot Token")
 			return
 		}
 
-		if conf.Bot.GroupId == 0 {
-			clog.Fatal("[Bot] Invalid Bot Group Id")
+		if conf.Manager.SecretKey == "" {
+			clog.Fatal("[Manager] Invalid Secret Key")
 			return
 		}
 
-		if conf.Bot.WebHook.Host == "" {
-			clog.Fatal("[Bot] Invalid WebHook Host")
+		switch conf.Manager.Mode {
+		case "", model.BotModeWebhook:
+			conf.Manager.Mode = model.BotModeWebhook
+
+			if conf.Manager.WebHook.Host == "" {
+				clog.Fatal("[Manager] Invalid WebHook Host")
+				return
+			}
+		case model.BotModePolling:
+			if conf.Cluster.Enabled {
+				clog.Fatal("[Manager] Polling mode is not supported in cluster mode")
+				return
+			}
+		default:
+			clog.Fatal("[Manager] Unknown Bot Mode")
 			return
 		}
 
-		err := bots.Load(conf.Bot)
+		err := bots.LoadManager(conf.Manager)
 		if err != nil {
-			clog.Fatal("[Bot][Initial] failed to init bot, error: ", err)
+			clog.Fatal("[Manager][Initial] failed to init bot, error: ", err)
 			return
 		}
 	}
 
 	cron.Start()
+	api.Load(conf.API.Token)
 
 	srv := &http.Server{
 		Handler:  webhook.Handler(),
@@ -194,6 +331,7 @@ func main() {
 	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGTRAP)
 
 	<-sigs
+	bots.Stop()
 	srv.Shutdown(context.Background())
 	clog.Message("Exiting")
 }
@@ -15,12 +15,17 @@ func InitDB(config config.Database) error {
 		return err
 	}
 
-	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger})
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger, TranslateError: true})
 	if err != nil {
 		return err
 	}
 
-	err = db.AutoMigrate(model.Topic{}, model.Msg{})
+	err = db.AutoMigrate(model.Topic{}, model.Msg{}, model.Offset{}, model.ManagedBot{}, model.Event{}, model.Lock{}, model.MediaGroup{}, model.MediaGroupItem{}, model.Archive{}, model.Appeal{}, model.Setting{}, model.Member{}, model.Audit{}, model.Snippet{})
+	if err != nil {
+		return err
+	}
+
+	err = createFullTextIndex(db)
 	if err != nil {
 		return err
 	}
@@ -28,3 +33,19 @@ func InitDB(config config.Database) error {
 	DB = db.Unscoped
 	return nil
 }
+
+// createFullTextIndex creates the native full-text index of archives on MySQL and Postgres.
+func createFullTextIndex(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "mysql":
+		if db.Migrator().HasIndex(&model.Archive{}, "idx_archives_content_fulltext") {
+			return nil
+		}
+
+		return db.Exec("CREATE FULLTEXT INDEX idx_archives_content_fulltext ON archives (content)").Error
+	case "postgres":
+		return db.Exec("CREATE INDEX IF NOT EXISTS idx_archives_content_fulltext ON archives USING GIN (to_tsvector('simple', content))").Error
+	default:
+		return nil
+	}
+}
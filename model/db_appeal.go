@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type Appeal struct {
+	Id int64 `gorm:"column:id; primaryKey; not null" json:"id"`
+
+	BotId        int64  `gorm:"column:bot_id; not null; index:idx_appeals_bot_user" json:"bot_id"`
+	UserId       int64  `gorm:"column:user_id; not null; index:idx_appeals_bot_user" json:"user_id"`
+	UserName     string `gorm:"column:user_name; not null" json:"user_name"`
+	LanguageCode string `gorm:"column:language_code; not null" json:"language_code"`
+	Content      string `gorm:"column:content; not null" json:"content"`
+
+	MessageId int `gorm:"column:message_id; not null; default: 0" json:"message_id"` // post in the appeals topic
+
+	Status     AppealStatus `gorm:"column:status; not null; default: 0" json:"status"`
+	ResolvedBy int64        `gorm:"column:resolved_by; not null; default: 0" json:"resolved_by"`
+	ResolvedAt int64        `gorm:"column:resolved_at; not null; default: 0" json:"resolved_at"`
+
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime" json:"created_at"`
+}
+
+func (*Appeal) TableName() string {
+	return "appeals"
+}
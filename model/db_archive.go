@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+type Archive struct {
+	Id int64 `gorm:"column:id; primaryKey; not null" json:"id"`
+
+	BotId    int64 `gorm:"column:bot_id; not null; index" json:"bot_id"`
+	UserId   int64 `gorm:"column:user_id; not null; index" json:"user_id"`
+	TopicId  int64 `gorm:"column:topic_id; not null; index" json:"topic_id"`
+	ThreadId int   `gorm:"column:thread_id; not null" json:"thread_id"`
+	EditOf   int64 `gorm:"column:edit_of; not null; default:0; index" json:"edit_of,omitempty"` // id of the edited archive
+
+	Direction  Direction `gorm:"column:direction; not null" json:"direction"`
+	UserMsgId  int       `gorm:"column:user_msg_id; not null" json:"user_msg_id"`
+	TopicMsgId int       `gorm:"column:topic_msg_id; not null" json:"topic_msg_id"`
+
+	SenderId   int64  `gorm:"column:sender_id; not null" json:"sender_id"`
+	SenderName string `gorm:"column:sender_name; not null" json:"sender_name"`
+
+	Content   string `gorm:"column:content; not null" json:"content"`   // text or caption
+	Entities  string `gorm:"column:entities; not null" json:"entities"` // JSON
+	MediaType string `gorm:"column:media_type; size:32; not null" json:"media_type"`
+	FileId    string `gorm:"column:file_id; not null" json:"file_id"`
+
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime" json:"created_at"` // sent or edited time
+}
+
+func (*Archive) TableName() string {
+	return "archives"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type Audit struct {
+	Id int64 `gorm:"column:id; primaryKey; not null" json:"id"`
+
+	BotId    int64       `gorm:"column:bot_id; not null; index:idx_audit_log_bot_target" json:"bot_id"`
+	ActorId  int64       `gorm:"column:actor_id; not null" json:"actor_id"` // AuditActorSystem or AuditActorAPI for non-members
+	TargetId int64       `gorm:"column:target_id; not null; index:idx_audit_log_bot_target" json:"target_id"`
+	Action   AuditAction `gorm:"column:action; not null" json:"action"`
+	Reason   string      `gorm:"column:reason; not null" json:"reason"`
+
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime; index" json:"created_at"`
+}
+
+func (*Audit) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type Event struct {
+	Id int64 `gorm:"column:id; primaryKey; not null"`
+
+	BotId    int64  `gorm:"column:bot_id; not null; index"`
+	Endpoint string `gorm:"column:endpoint; not null"`
+	Event    string `gorm:"column:event; not null"`
+	Payload  string `gorm:"column:payload; not null"`
+
+	Status      EventStatus `gorm:"column:status; not null; default: 0; index"`
+	Attempts    int         `gorm:"column:attempts; not null; default: 0"`
+	NextAttempt int64       `gorm:"column:next_attempt; not null; default: 0; index"`
+	LastStatus  int         `gorm:"column:last_status; not null; default: 0"`
+	LastError   string      `gorm:"column:last_error; not null"`
+
+	CreatedAt   time.Time `gorm:"column:created_at; not null; autoCreateTime" json:"created_at"`
+	DeliveredAt int64     `gorm:"column:delivered_at; not null; default: 0"`
+}
+
+func (*Event) TableName() string {
+	return "event_outbox"
+}
@@ -0,0 +1,12 @@
+package model
+
+type Lock struct {
+	Name string `gorm:"column:name; primaryKey; size:191; not null"`
+
+	Owner     string `gorm:"column:owner; not null"`
+	ExpiresAt int64  `gorm:"column:expires_at; not null"`
+}
+
+func (*Lock) TableName() string {
+	return "cluster_locks"
+}
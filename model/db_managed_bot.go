@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type ManagedBot struct {
+	Id int64 `gorm:"column:id; primaryKey; not null"`
+
+	BotId    int64  `gorm:"column:bot_id; not null; uniqueIndex"`
+	UserName string `gorm:"column:username; not null"`
+	OwnerId  int64  `gorm:"column:owner_id; not null; index"`
+
+	Token        string `gorm:"column:token; not null" json:"-"` // Encrypted
+	GroupId      int64  `gorm:"column:group_id; not null"`
+	LanguageCode string `gorm:"column:language_code; not null"`
+
+	IsPaused bool `gorm:"column:is_paused; not null"`
+
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime" json:"created_at"`
+}
+
+func (*ManagedBot) TableName() string {
+	return "managed_bots"
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// MediaGroup is claimed by the node which relays the album in cluster mode.
+type MediaGroup struct {
+	Id int64 `gorm:"column:id; primaryKey; not null"`
+
+	BotId        int64  `gorm:"column:bot_id; not null; uniqueIndex:idx_media_groups_bot_group"`
+	MediaGroupId string `gorm:"column:media_group_id; size:191; not null; uniqueIndex:idx_media_groups_bot_group"`
+
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime; index" json:"created_at"`
+}
+
+func (*MediaGroup) TableName() string {
+	return "media_groups"
+}
+
+type MediaGroupItem struct {
+	Id int64 `gorm:"column:id; primaryKey; not null"`
+
+	BotId        int64  `gorm:"column:bot_id; not null; uniqueIndex:idx_media_group_items_message"`
+	MediaGroupId string `gorm:"column:media_group_id; size:191; not null; uniqueIndex:idx_media_group_items_message"`
+	MessageId    int    `gorm:"column:message_id; not null; uniqueIndex:idx_media_group_items_message"`
+	Message      string `gorm:"column:message; not null"` // JSON
+
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime; index" json:"created_at"`
+}
+
+func (*MediaGroupItem) TableName() string {
+	return "media_group_items"
+}
@@ -0,0 +1,12 @@
+package model
+
+type Member struct {
+	BotId  int64 `gorm:"column:bot_id; primaryKey; not null" json:"bot_id"`
+	UserId int64 `gorm:"column:user_id; primaryKey; not null" json:"user_id"`
+
+	Role Role `gorm:"column:role; not null; default: 0" json:"role"` // assigned role, overrides the derived one
+}
+
+func (*Member) TableName() string {
+	return "members"
+}
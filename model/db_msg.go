@@ -3,12 +3,13 @@ package model
 import "time"
 
 type Msg struct {
-	Id int64 `gorm:"column:id; primaryKey; not null"`
+	Id int64 `gorm:"column:id; primaryKey; not null" json:"id"`
 
-	TopicId int64 `gorm:"column:topic_id; not null"`
+	BotId   int64 `gorm:"column:bot_id; not null; default: 0; index" json:"bot_id"`
+	TopicId int64 `gorm:"column:topic_id; not null" json:"topic_id"`
 
-	UserMsgId  int `gorm:"column:user_msg_id; not null"`
-	TopicMsgId int `gorm:"column:topic_msg_id; not null"`
+	UserMsgId  int `gorm:"column:user_msg_id; not null" json:"user_msg_id"`
+	TopicMsgId int `gorm:"column:topic_msg_id; not null" json:"topic_msg_id"`
 
 	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime" json:"created_at"`
 }
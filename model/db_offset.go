@@ -0,0 +1,11 @@
+package model
+
+type Offset struct {
+	BotId int64 `gorm:"column:bot_id; primaryKey; autoIncrement:false; not null"`
+
+	UpdateId int `gorm:"column:update_id; not null"`
+}
+
+func (*Offset) TableName() string {
+	return "offsets"
+}
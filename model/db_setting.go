@@ -0,0 +1,11 @@
+package model
+
+type Setting struct {
+	BotId int64  `gorm:"column:bot_id; primaryKey; not null"`
+	Key   string `gorm:"column:key; primaryKey; size:191; not null"`
+	Value string `gorm:"column:value; not null"`
+}
+
+func (*Setting) TableName() string {
+	return "settings"
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type Snippet struct {
+	Id int64 `gorm:"column:id; primaryKey; not null" json:"id"`
+
+	BotId        int64  `gorm:"column:bot_id; not null; uniqueIndex:idx_snippets_bot_name_language" json:"bot_id"`
+	Name         string `gorm:"column:name; not null; size:64; uniqueIndex:idx_snippets_bot_name_language" json:"name"`
+	LanguageCode string `gorm:"column:language_code; not null; size:16; default: ''; uniqueIndex:idx_snippets_bot_name_language" json:"language_code"` // empty for the default variant
+
+	Content   string `gorm:"column:content; not null" json:"content"`
+	Entities  string `gorm:"column:entities; not null" json:"entities"` // JSON encoded
+	MediaType string `gorm:"column:media_type; not null" json:"media_type"`
+	FileId    string `gorm:"column:file_id; not null" json:"file_id"`
+
+	CreatedBy int64     `gorm:"column:created_by; not null" json:"created_by"`
+	CreatedAt time.Time `gorm:"column:created_at; not null; autoCreateTime" json:"created_at"`
+}
+
+func (*Snippet) TableName() string {
+	return "snippets"
+}
@@ -1,19 +1,45 @@
 package model
 
+import "time"
+
 type Topic struct {
-	Id int64 `gorm:"column:id; primaryKey; not null"`
+	Id int64 `gorm:"column:id; primaryKey; not null" json:"id"`
+
+	BotId   int64 `gorm:"column:bot_id; not null; default: 0; index; uniqueIndex:idx_topics_bot_user" json:"bot_id"`
+	UserId  int64 `gorm:"column:user_id; not null; uniqueIndex:idx_topics_bot_user" json:"user_id"`
+	TopicId int   `gorm:"column:topic_id; not null" json:"topic_id"`
+
+	Verification  Verification `gorm:"column:verification; not null; default: 0" json:"verification"`
+	ChallangeId   uint64       `gorm:"column:challange_id; not null; default: 0" json:"-"`
+	ChallangeSent int64        `gorm:"column:challange_sent; not null; default: 0" json:"challange_sent"`
+	ChallangeTry  int          `gorm:"column:challange_try; not null; default: 0" json:"challange_try"` // typed answer attempts of the challange
+
+	CaptchaFailures int   `gorm:"column:captcha_failures; not null; default: 0" json:"captcha_failures"`
+	CaptchaRetryAt  int64 `gorm:"column:captcha_retry_at; not null; default: 0" json:"captcha_retry_at"` // cooldown before a new challange
 
-	UserId  int64 `gorm:"column:user_id; not null"`
-	TopicId int   `gorm:"column:topic_id; not null"`
+	IsBan     bool   `gorm:"column:is_ban; not null" json:"is_ban"`
+	BanUntil  int64  `gorm:"column:ban_until; not null; default: 0; index" json:"ban_until"` // 0 for permanent
+	BanReason string `gorm:"column:ban_reason; not null; default: ''" json:"ban_reason"`
 
-	Verification  Verification `gorm:"column:verification; not null; default: 0"`
-	ChallangeId   uint64       `gorm:"column:challange_id; not null; default: 0"`
-	ChallangeSent int64        `gorm:"column:challange_sent; not null; default: 0"`
+	SenderMsgId int  `gorm:"column:sender_msg_id; not null; default: 0" json:"sender_msg_id"` // the pinned sender card
+	IsMuted     bool `gorm:"column:is_muted; not null; default: false" json:"is_muted"`       // relay silently
+	AwaySent    bool `gorm:"column:away_sent; not null; default: false" json:"away_sent"`     // the away reply is sent, reset by the next reply
 
-	IsBan        bool   `gorm:"column:is_ban; not null"`
-	LanguageCode string `gorm:"column:language_code; not null"`
+	State TicketState `gorm:"column:state; not null; default: 0; index" json:"state"`
+
+	LanguageCode string `gorm:"column:language_code; not null" json:"language_code"`
+
+	Version int64 `gorm:"column:version; not null; default: 0" json:"version"`
 }
 
 func (*Topic) TableName() string {
 	return "topics"
 }
+
+// BanExpiry returns the expiry of the ban, zero for permanent.
+func (topic *Topic) BanExpiry() time.Time {
+	if topic.BanUntil == 0 {
+		return time.Time{}
+	}
+	return time.Unix(topic.BanUntil, 0)
+}
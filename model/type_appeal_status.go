@@ -0,0 +1,23 @@
+package model
+
+import (
+	"database/sql/driver"
+
+	"gorm.io/gorm/schema"
+)
+
+type AppealStatus uint8
+
+const (
+	AppealPending AppealStatus = iota
+	AppealApproved
+	AppealRejected
+)
+
+func (AppealStatus) GormDataType() string {
+	return string(schema.Uint)
+}
+
+func (p AppealStatus) Value() (driver.Value, error) {
+	return int64(p), nil
+}
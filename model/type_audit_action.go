@@ -0,0 +1,27 @@
+package model
+
+type AuditAction string
+
+const (
+	AuditBan            AuditAction = "ban"
+	AuditUnban          AuditAction = "unban"
+	AuditTerminate      AuditAction = "terminate"
+	AuditTopicReopened  AuditAction = "topic_reopened"
+	AuditBlocked        AuditAction = "blocked"
+	AuditCaptchaPassed  AuditAction = "captcha_passed"
+	AuditCaptchaFailed  AuditAction = "captcha_failed"
+	AuditAppealApproved AuditAction = "appeal_approved"
+	AuditAppealRejected AuditAction = "appeal_rejected"
+	AuditMute           AuditAction = "mute"
+	AuditUnmute         AuditAction = "unmute"
+	AuditRoleAssigned   AuditAction = "role_assigned"
+	AuditNote           AuditAction = "note"
+	AuditTicketOpen     AuditAction = "ticket_open"
+	AuditTicketPending  AuditAction = "ticket_pending"
+	AuditTicketResolved AuditAction = "ticket_resolved"
+)
+
+const (
+	AuditActorSystem int64 = 0  // automatic actions
+	AuditActorAPI    int64 = -1 // admin API
+)
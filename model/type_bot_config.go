@@ -1,11 +1,55 @@
 package model
 
+const (
+	CaptchaAnswerButton = "button"
+	CaptchaAnswerText   = "text"
+)
+
+const (
+	BotModeWebhook = "webhook"
+	BotModePolling = "polling"
+)
+
 type BotConfig struct {
 	Token        string
 	GroupId      int64
 	LanguageCode string
 
+	Mode string // webhook, polling
+
 	WebHook struct {
 		Host string
 	}
+
+	Archive bool // keep the content of relayed messages
+
+	Captcha struct {
+		Type     string // math, image
+		Answer   string // button, text
+		Attempts int    // attempts of text answer
+
+		LockoutAfter int // failures before the cooldown
+		Lockout      int // seconds of the first cooldown, doubled on each failure
+		BanAfter     int // failures before the user is banned, 0 to disable
+
+		Duration int           // seconds to complete the challange
+		Rules    []CaptchaRule // bypass policy
+		DryRun   bool          // log the users would be challenged instead
+	}
+
+	Appeal struct {
+		Cooldown int // seconds between appeals of a banned user
+	}
+
+	Schedule Schedule // business hours, the away reply is sent outside of them
+
+	FAQ []FAQRule // evaluated in order before the message is forwarded
+
+	Notes struct {
+		Prefix string // messages starting with the prefix are internal notes, /note is always available
+	}
+
+	Roles struct {
+		Default string // role of the members which are not administrators, observer by default
+	}
 }
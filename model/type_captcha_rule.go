@@ -0,0 +1,34 @@
+package model
+
+const (
+	CaptchaRuleAlways    = "always"    // challenge everyone
+	CaptchaRuleNever     = "never"     // bypass everyone
+	CaptchaRulePremium   = "premium"   // bypass premium users
+	CaptchaRuleUserId    = "user_id"   // bypass ids at or below UserId
+	CaptchaRuleUsername  = "username"  // bypass users with at least Usernames active usernames
+	CaptchaRuleLanguage  = "language"  // bypass users with a language code in LanguageCodes
+	CaptchaRulePhoto     = "photo"     // bypass users with a profile photo
+	CaptchaRuleAllowlist = "allowlist" // bypass users in UserIds
+)
+
+// CaptchaRule is a rule of the captcha policy, rules are evaluated in order and the first matched rule decides.
+// Users matched no rule are challenged.
+type CaptchaRule struct {
+	Type string
+
+	UserId        int64
+	Usernames     int
+	LanguageCodes []string
+	UserIds       []int64
+}
+
+func (rule *CaptchaRule) IsValid() bool {
+	switch rule.Type {
+	case CaptchaRuleAlways, CaptchaRuleNever, CaptchaRulePremium, CaptchaRuleUsername, CaptchaRuleLanguage, CaptchaRulePhoto, CaptchaRuleAllowlist:
+		return true
+	case CaptchaRuleUserId:
+		return rule.UserId > 0
+	default:
+		return false
+	}
+}
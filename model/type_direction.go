@@ -0,0 +1,36 @@
+package model
+
+import (
+	"database/sql/driver"
+
+	"gorm.io/gorm/schema"
+)
+
+type Direction uint8
+
+const (
+	DirectionIncoming Direction = iota // user to topic
+	DirectionOutgoing                  // topic to user
+	DirectionNote                      // internal note, never sent to user
+)
+
+func (Direction) GormDataType() string {
+	return string(schema.Uint)
+}
+
+func (p Direction) Value() (driver.Value, error) {
+	return int64(p), nil
+}
+
+func (p Direction) String() string {
+	switch p {
+	case DirectionIncoming:
+		return "user"
+	case DirectionOutgoing:
+		return "admin"
+	case DirectionNote:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
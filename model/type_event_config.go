@@ -0,0 +1,8 @@
+package model
+
+type EventEndpoint struct {
+	URL    string
+	Secret string
+
+	Events []string // Empty means all events
+}
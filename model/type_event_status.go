@@ -0,0 +1,23 @@
+package model
+
+import (
+	"database/sql/driver"
+
+	"gorm.io/gorm/schema"
+)
+
+type EventStatus uint8
+
+const (
+	EventPending EventStatus = iota
+	EventDelivered
+	EventFailed
+)
+
+func (EventStatus) GormDataType() string {
+	return string(schema.Uint)
+}
+
+func (p EventStatus) Value() (driver.Value, error) {
+	return int64(p), nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// FAQRule answers the user automatically, it matches if any keyword is contained or the regex matches.
+type FAQRule struct {
+	Keywords []string          // case-insensitive
+	Regex    string            // Go regular expression
+	Answers  map[string]string // language code -> answer, "" for the default answer
+
+	regexp *regexp.Regexp
+}
+
+// Compile validates the rule, it must be called before Match.
+func (rule *FAQRule) Compile() error {
+	if len(rule.Keywords) == 0 && rule.Regex == "" {
+		return errors.New("keywords or regex required")
+	}
+
+	if len(rule.Answers) == 0 {
+		return errors.New("answers required")
+	}
+
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return err
+		}
+		rule.regexp = re
+	}
+
+	return nil
+}
+
+func (rule *FAQRule) Match(text string) bool {
+	lower := strings.ToLower(text)
+	for _, keyword := range rule.Keywords {
+		if keyword != "" && strings.Contains(lower, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+
+	return rule.regexp != nil && rule.regexp.MatchString(text)
+}
+
+// Answer returns the answer in the language, the default answer is the fallback.
+func (rule *FAQRule) Answer(languageCode string) (string, bool) {
+	if answer, ok := rule.Answers[languageCode]; ok {
+		return answer, true
+	}
+
+	answer, ok := rule.Answers[""]
+	return answer, ok
+}
@@ -0,0 +1,16 @@
+package model
+
+type ManagerConfig struct {
+	Token        string
+	LanguageCode string
+
+	Mode string // webhook, polling
+
+	WebHook struct {
+		Host string
+	}
+
+	SecretKey string // Used to encrypt the tokens of registered bots
+
+	Archive bool // keep the content of relayed messages of registered bots
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"database/sql/driver"
+
+	"gorm.io/gorm/schema"
+)
+
+// Role is the permission of a group member, a higher role has all the permissions of the lower ones.
+type Role uint8
+
+const (
+	RoleObserver  Role = iota // read only
+	RoleAgent                 // reply to users, search and export
+	RoleModerator             // ban, unban and terminate
+	RoleOwner                 // assign roles
+)
+
+var roleNames = map[Role]string{
+	RoleObserver:  "observer",
+	RoleAgent:     "agent",
+	RoleModerator: "moderator",
+	RoleOwner:     "owner",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// ParseRole parses the name of a role.
+func ParseRole(name string) (Role, bool) {
+	for role, roleName := range roleNames {
+		if roleName == name {
+			return role, true
+		}
+	}
+	return 0, false
+}
+
+func (Role) GormDataType() string {
+	return string(schema.Uint)
+}
+
+func (p Role) Value() (driver.Value, error) {
+	return int64(p), nil
+}
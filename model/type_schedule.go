@@ -0,0 +1,156 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is the business hours, the bot is always open if no hours are configured.
+type Schedule struct {
+	Timezone string            // IANA time zone, UTC by default
+	Hours    map[string]string // weekday (mon, tue, ...) -> "09:00-12:00,13:00-18:00", missing days are closed
+	Holidays []string          // closed dates, 2006-01-02
+
+	location *time.Location
+	hours    [7][][2]int // minutes of the day, [open, close)
+	holidays map[string]bool
+}
+
+// Compile validates the schedule, it must be called before use.
+func (schedule *Schedule) Compile() error {
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return err
+	}
+	schedule.location = location
+
+	for day, hours := range schedule.Hours {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", day)
+		}
+
+		for _, span := range strings.Split(hours, ",") {
+			open, close, ok := strings.Cut(strings.TrimSpace(span), "-")
+			if !ok {
+				return fmt.Errorf("invalid hours %q", span)
+			}
+
+			start, err := parseClock(open)
+			if err != nil {
+				return err
+			}
+
+			end, err := parseClock(close)
+			if err != nil {
+				return err
+			}
+
+			if start >= end {
+				return fmt.Errorf("invalid hours %q", span)
+			}
+
+			schedule.hours[weekday] = append(schedule.hours[weekday], [2]int{start, end})
+		}
+	}
+
+	for weekday := range schedule.hours {
+		slices.SortFunc(schedule.hours[weekday], func(a, b [2]int) int {
+			return a[0] - b[0]
+		})
+	}
+
+	schedule.holidays = make(map[string]bool, len(schedule.Holidays))
+	for _, date := range schedule.Holidays {
+		_, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return err
+		}
+		schedule.holidays[date] = true
+	}
+
+	return nil
+}
+
+// parseClock parses 15:04 into minutes of the day, 24:00 is allowed for the end of the day.
+func parseClock(clock string) (int, error) {
+	clock = strings.TrimSpace(clock)
+	if clock == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, errors.New("invalid time " + clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (schedule *Schedule) IsEnabled() bool {
+	return len(schedule.Hours) != 0
+}
+
+// Location returns the time zone of the schedule.
+func (schedule *Schedule) Location() *time.Location {
+	if schedule.location == nil {
+		return time.UTC
+	}
+	return schedule.location
+}
+
+func (schedule *Schedule) IsOpen(t time.Time) bool {
+	if !schedule.IsEnabled() {
+		return true
+	}
+
+	t = t.In(schedule.Location())
+	if schedule.holidays[t.Format(time.DateOnly)] {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, span := range schedule.hours[t.Weekday()] {
+		if minute >= span[0] && minute < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the next opening time after t, zero if it is not open within a year.
+func (schedule *Schedule) NextOpen(t time.Time) time.Time {
+	if !schedule.IsEnabled() || schedule.IsOpen(t) {
+		return t
+	}
+
+	t = t.In(schedule.Location())
+	year, month, day := t.Date()
+	for i := 0; i <= 366; i++ {
+		date := time.Date(year, month, day+i, 0, 0, 0, 0, schedule.Location())
+		if schedule.holidays[date.Format(time.DateOnly)] {
+			continue
+		}
+
+		for _, span := range schedule.hours[date.Weekday()] {
+			open := time.Date(date.Year(), date.Month(), date.Day(), span[0]/60, span[0]%60, 0, 0, schedule.Location())
+			if open.After(t) {
+				return open
+			}
+		}
+	}
+
+	return time.Time{}
+}
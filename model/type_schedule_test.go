@@ -0,0 +1,149 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func testSchedule(t *testing.T) *Schedule {
+	schedule := &Schedule{
+		Timezone: "Asia/Shanghai",
+		Hours: map[string]string{
+			"Mon": "13:00-18:00, 09:00-12:00",
+			"tue": "09:00-18:00",
+			"wed": "09:00-18:00",
+			"fri": "09:00-18:00",
+			"sat": "00:00-24:00",
+		},
+		Holidays: []string{"2024-01-02"},
+	}
+
+	err := schedule.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return schedule
+}
+
+func TestScheduleCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		wantErr  bool
+	}{
+		{"empty", Schedule{}, false},
+		{"valid", Schedule{Timezone: "Asia/Shanghai", Hours: map[string]string{"mon": "09:00-12:00,13:00-24:00"}, Holidays: []string{"2024-01-01"}}, false},
+		{"unknown time zone", Schedule{Timezone: "Mars/Olympus"}, true},
+		{"unknown weekday", Schedule{Hours: map[string]string{"monday": "09:00-18:00"}}, true},
+		{"missing end", Schedule{Hours: map[string]string{"mon": "09:00"}}, true},
+		{"reversed span", Schedule{Hours: map[string]string{"mon": "18:00-09:00"}}, true},
+		{"empty span", Schedule{Hours: map[string]string{"mon": "09:00-09:00"}}, true},
+		{"invalid time", Schedule{Hours: map[string]string{"mon": "09:00-25:00"}}, true},
+		{"24:00 start", Schedule{Hours: map[string]string{"mon": "24:00-24:00"}}, true},
+		{"invalid holiday", Schedule{Holidays: []string{"2024-13-01"}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.schedule.Compile()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleIsOpen(t *testing.T) {
+	schedule := testSchedule(t)
+	location := schedule.Location()
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, location)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want bool
+	}{
+		{"before opening", at(1, 8, 59), false},
+		{"opening", at(1, 9, 0), true},
+		{"lunch break", at(1, 12, 0), false},
+		{"after lunch", at(1, 13, 0), true},
+		{"closing", at(1, 18, 0), false},
+		{"holiday", at(2, 10, 0), false},
+		{"closed weekday", at(4, 10, 0), false},
+		{"until 24:00", at(6, 23, 59), true},
+		{"midnight after 24:00", at(7, 0, 0), false},
+		{"in UTC", time.Date(2024, time.January, 1, 1, 0, 0, 0, time.UTC), true},
+		{"in UTC on the previous day", time.Date(2023, time.December, 31, 16, 30, 0, 0, time.UTC), false},
+		{"in UTC on a holiday", time.Date(2024, time.January, 2, 2, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := schedule.IsOpen(test.time); got != test.want {
+				t.Fatalf("IsOpen(%s) = %v, want %v", test.time, got, test.want)
+			}
+		})
+	}
+
+	var always Schedule
+	if !always.IsOpen(at(1, 3, 0)) {
+		t.Fatal("IsOpen() = false without hours, want true")
+	}
+}
+
+func TestScheduleNextOpen(t *testing.T) {
+	schedule := testSchedule(t)
+	location := schedule.Location()
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, location)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want time.Time
+	}{
+		{"already open", at(1, 10, 0), at(1, 10, 0)},
+		{"before opening", at(1, 7, 0), at(1, 9, 0)},
+		{"lunch break", at(1, 12, 30), at(1, 13, 0)},
+		{"skips holiday", at(1, 19, 0), at(3, 9, 0)},
+		{"skips closed weekday", at(3, 18, 0), at(5, 9, 0)},
+		{"all day after 24:00", at(5, 18, 0), at(6, 0, 0)},
+		{"next week", at(7, 10, 0), at(8, 9, 0)},
+		{"in UTC", time.Date(2024, time.January, 1, 4, 30, 0, 0, time.UTC), at(1, 13, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := schedule.NextOpen(test.time); !got.Equal(test.want) {
+				t.Fatalf("NextOpen(%s) = %s, want %s", test.time, got, test.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextOpenNever(t *testing.T) {
+	schedule := &Schedule{
+		Timezone: "UTC",
+		Hours:    map[string]string{"mon": "09:00-18:00"},
+	}
+
+	// Every Monday is a holiday
+	for date := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC); date.Year() < 2026; date = date.AddDate(0, 0, 7) {
+		schedule.Holidays = append(schedule.Holidays, date.Format(time.DateOnly))
+	}
+
+	err := schedule.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	if got := schedule.NextOpen(now); !got.IsZero() {
+		t.Fatalf("NextOpen(%s) = %s, want zero", now, got)
+	}
+}
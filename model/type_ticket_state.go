@@ -0,0 +1,23 @@
+package model
+
+import (
+	"database/sql/driver"
+
+	"gorm.io/gorm/schema"
+)
+
+type TicketState uint8
+
+const (
+	TicketOpen     TicketState = iota
+	TicketPending              // waiting on the user
+	TicketResolved             // the forum topic is closed
+)
+
+func (TicketState) GormDataType() string {
+	return string(schema.Uint)
+}
+
+func (p TicketState) Value() (driver.Value, error) {
+	return int64(p), nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"Topicgram/services/bots"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+var token string
+
+func Load(apiToken string) {
+	token = apiToken
+}
+
+func Register(router *gin.Engine) {
+	if token == "" {
+		return
+	}
+
+	v1 := router.Group("/topicgram/api/v1", authorize)
+	{
+		v1.GET("/metrics", metrics)
+
+		bot := v1.Group("/bots/:botId", loadBot)
+
+		bot.GET("/topics", listTopics)
+		bot.GET("/topics/:userId/messages", listMessages)
+
+		bot.POST("/users/:userId/ban", banUser)
+		bot.POST("/users/:userId/unban", unbanUser)
+		bot.POST("/users/:userId/terminate", terminateUser)
+		bot.POST("/users/:userId/messages", sendMessage)
+	}
+}
+
+func authorize(c *gin.Context) {
+	authorization, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(authorization), []byte(token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+}
+
+func loadBot(c *gin.Context) {
+	bot_id, err := strconv.ParseInt(c.Param("botId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return
+	}
+
+	bot, ok := bots.Get(bot_id)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return
+	}
+
+	c.Set("bot", bot)
+}
+
+func getBot(c *gin.Context) *bots.Bot {
+	return c.MustGet("bot").(*bots.Bot)
+}
+
+func getUserId(c *gin.Context) (int64, bool) {
+	user_id, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+
+	return user_id, true
+}
+
+func paginate(c *gin.Context) (page, perPage int) {
+	page, _ = strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	perPage, _ = strconv.Atoi(c.Query("per_page"))
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return
+}
+
+func paginated(c *gin.Context, data any, page, perPage int, total int64) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":     data,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	})
+}
+
+func metrics(c *gin.Context) {
+	stats, ok := bots.GetDispatcherStats()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dispatcher not started"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"dispatcher": stats}})
+}
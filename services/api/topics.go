@@ -0,0 +1,103 @@
+package api
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+func listTopics(c *gin.Context) {
+	bot := getBot(c)
+	page, perPage := paginate(c)
+
+	query := DB().Model(model.Topic{}).Where("bot_id", bot.Self.ID)
+
+	if user_id := c.Query("user_id"); user_id != "" {
+		id, err := strconv.ParseInt(user_id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+
+		query = query.Where("user_id", id)
+	}
+
+	if is_ban := c.Query("is_ban"); is_ban != "" {
+		banned, err := strconv.ParseBool(is_ban)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid is_ban"})
+			return
+		}
+
+		query = query.Where("is_ban", banned)
+	}
+
+	if language_code := c.Query("language_code"); language_code != "" {
+		query = query.Where("language_code", language_code)
+	}
+
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		clog.Errorf("[API] execute error: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	topics := make([]model.Topic, 0, perPage)
+	err = query.Order("id").Offset((page - 1) * perPage).Limit(perPage).Find(&topics).Error
+	if err != nil {
+		clog.Errorf("[API] execute error: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	paginated(c, topics, page, perPage, total)
+}
+
+func listMessages(c *gin.Context) {
+	bot := getBot(c)
+	page, perPage := paginate(c)
+
+	user_id, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	var topic model.Topic
+	err := DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		clog.Errorf("[API] execute error: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	if topic.Id == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic not found"})
+		return
+	}
+
+	query := DB().Model(model.Msg{}).Where("topic_id", topic.Id)
+
+	var total int64
+	err = query.Count(&total).Error
+	if err != nil {
+		clog.Errorf("[API] execute error: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	msgs := make([]model.Msg, 0, perPage)
+	err = query.Order("id").Offset((page - 1) * perPage).Limit(perPage).Find(&msgs).Error
+	if err != nil {
+		clog.Errorf("[API] execute error: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	paginated(c, msgs, page, perPage, total)
+}
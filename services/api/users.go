@@ -0,0 +1,235 @@
+package api
+
+import (
+	"Topicgram/model"
+	"Topicgram/services/bots"
+	"Topicgram/utils"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/gin-gonic/gin"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+type messageRequest struct {
+	Type string `json:"type"` // text, photo, video, document, audio, animation, voice
+
+	Text     string                 `json:"text"`
+	Entities []botapi.MessageEntity `json:"entities"`
+
+	Media           string                 `json:"media"` // file_id or url
+	Caption         string                 `json:"caption"`
+	CaptionEntities []botapi.MessageEntity `json:"caption_entities"`
+}
+
+type banRequest struct {
+	Duration string `json:"duration"` // e.g. 30m, 12h, 7d, empty for permanent
+	Reason   string `json:"reason"`
+}
+
+func banUser(c *gin.Context) {
+	user_id, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	var request banRequest
+	if c.Request.ContentLength != 0 {
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var duration time.Duration
+	if request.Duration != "" {
+		duration, ok = utils.ParseDuration(request.Duration)
+		if !ok || duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration"})
+			return
+		}
+	}
+
+	topic, err := getBot(c).Ban(user_id, model.AuditActorAPI, duration, request.Reason)
+	if err != nil {
+		if errors.Is(err, bots.ErrTopicConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		clog.Errorf("[API] ban user %d failed, error: %s", user_id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": topic})
+}
+
+func unbanUser(c *gin.Context) {
+	user_id, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	topic, err := getBot(c).Unban(user_id, model.AuditActorAPI)
+	if err != nil {
+		if errors.Is(err, bots.ErrTopicConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		clog.Errorf("[API] unban user %d failed, error: %s", user_id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": topic})
+}
+
+func terminateUser(c *gin.Context) {
+	user_id, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	err := getBot(c).Terminate(user_id, model.AuditActorAPI)
+	if err != nil {
+		if errors.Is(err, bots.ErrTopicConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		clog.Errorf("[API] terminate user %d failed, error: %s", user_id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": nil})
+}
+
+func sendMessage(c *gin.Context) {
+	user_id, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	var request messageRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	build, ok := request.build()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message"})
+		return
+	}
+
+	msg, err := getBot(c).Reply(user_id, build)
+	if err != nil {
+		switch {
+		case errors.Is(err, bots.ErrTopicNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, bots.ErrUserBanned):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			clog.Errorf("[API] send message to user %d failed, error: %s", user_id, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": msg})
+}
+
+func (request *messageRequest) file() botapi.RequestFileData {
+	if isURL(request.Media) {
+		return botapi.FileURL(request.Media)
+	}
+
+	return botapi.FileID(request.Media)
+}
+
+func (request *messageRequest) build() (func(userChat botapi.BaseChat) botapi.Chattable, bool) {
+	switch request.Type {
+	case "", "text":
+		if request.Text == "" {
+			return nil, false
+		}
+
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.MessageConfig{
+				BaseChat: userChat,
+				Text:     request.Text,
+				Entities: request.Entities,
+			}
+		}, true
+	}
+
+	if request.Media == "" {
+		return nil, false
+	}
+
+	switch request.Type {
+	case "photo":
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.PhotoConfig{
+				BaseFile:        botapi.BaseFile{BaseChat: userChat, File: request.file()},
+				Caption:         request.Caption,
+				CaptionEntities: request.CaptionEntities,
+			}
+		}, true
+	case "video":
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.VideoConfig{
+				BaseFile:        botapi.BaseFile{BaseChat: userChat, File: request.file()},
+				Caption:         request.Caption,
+				CaptionEntities: request.CaptionEntities,
+			}
+		}, true
+	case "document":
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.DocumentConfig{
+				BaseFile:        botapi.BaseFile{BaseChat: userChat, File: request.file()},
+				Caption:         request.Caption,
+				CaptionEntities: request.CaptionEntities,
+			}
+		}, true
+	case "audio":
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.AudioConfig{
+				BaseFile:        botapi.BaseFile{BaseChat: userChat, File: request.file()},
+				Caption:         request.Caption,
+				CaptionEntities: request.CaptionEntities,
+			}
+		}, true
+	case "animation":
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.AnimationConfig{
+				BaseFile:        botapi.BaseFile{BaseChat: userChat, File: request.file()},
+				Caption:         request.Caption,
+				CaptionEntities: request.CaptionEntities,
+			}
+		}, true
+	case "voice":
+		return func(userChat botapi.BaseChat) botapi.Chattable {
+			return botapi.VoiceConfig{
+				BaseFile:        botapi.BaseFile{BaseChat: userChat, File: request.file()},
+				Caption:         request.Caption,
+				CaptionEntities: request.CaptionEntities,
+			}
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
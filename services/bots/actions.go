@@ -0,0 +1,380 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"Topicgram/services/events"
+	"errors"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTopicNotFound = errors.New("topic not found")
+	ErrUserBanned    = errors.New("user is banned")
+	ErrTopicConflict = errors.New("topic is changed concurrently")
+)
+
+// ban deletes the forum topic of the user and bans the user for the duration, 0 for permanent.
+// The caller must hold the lock of the user.
+func (bot *Bot) ban(topic *model.Topic, actor int64, duration time.Duration, reason string) error {
+	topic.BanUntil = 0
+	if duration > 0 {
+		topic.BanUntil = time.Now().Add(duration).Unix()
+	}
+	topic.BanReason = reason
+
+	if topic.TopicId != 0 {
+		bot.Request(botapi.DeleteForumTopicConfig{
+			BaseForum: botapi.BaseForum{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: bot.GroupId,
+				},
+				MessageThreadID: topic.TopicId,
+			},
+		})
+		DB().Model(model.Msg{}).Where("topic_id", topic.Id).Delete(nil)
+		topic.TopicId = 0
+		topic.SenderMsgId = 0
+	}
+
+	err := banTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	bot.audit(actor, topic.UserId, model.AuditBan, topic.BanReason)
+	bot.publish(events.UserBanned, topic, map[string]any{
+		"reason":    topic.BanReason,
+		"ban_until": topic.BanUntil,
+	})
+	return nil
+}
+
+// unban reopens the forum topic of the user and unbans the user, the caller must hold the lock of the user.
+func (bot *Bot) unban(topic *model.Topic, actor int64, reason string) error {
+	if topic.TopicId != 0 {
+		bot.Request(botapi.ReopenForumTopicConfig{
+			BaseForum: botapi.BaseForum{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: bot.GroupId,
+				},
+				MessageThreadID: topic.TopicId,
+			},
+		})
+	}
+
+	err := unbanTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	bot.audit(actor, topic.UserId, model.AuditUnban, reason)
+	bot.publish(events.UserUnbanned, topic, nil)
+	return nil
+}
+
+// terminate deletes the forum topic of the user and ends the conversation, the caller must hold the lock of the user.
+func (bot *Bot) terminate(topic *model.Topic, actor int64) error {
+	if topic.TopicId != 0 {
+		bot.Request(botapi.DeleteForumTopicConfig{
+			BaseForum: botapi.BaseForum{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: bot.GroupId,
+				},
+				MessageThreadID: topic.TopicId,
+			},
+		})
+	}
+
+	topicId := topic.TopicId
+
+	err := terminateTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	bot.audit(actor, topic.UserId, model.AuditTerminate, "")
+	bot.publish(events.UserTerminated, topic, map[string]any{
+		"topic_id": topicId,
+	})
+	return nil
+}
+
+func (bot *Bot) Ban(user_id, actor int64, duration time.Duration, reason string) (model.Topic, error) {
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(user_id)
+	if err != nil {
+		return model.Topic{}, err
+	}
+	defer bot.topics.Unlock(user_id)
+
+	var topic model.Topic
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return topic, err
+	}
+
+	if topic.IsBan {
+		return topic, nil
+	}
+
+	topic.BotId = bot.Self.ID
+	topic.UserId = user_id
+
+	err = bot.ban(&topic, actor, duration, reason)
+	if err != nil {
+		return topic, err
+	}
+
+	userChat := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	}
+	botChat := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: bot.GroupId,
+		},
+	}
+
+	bot.sendBanned(userChat, i18n.GetOrDefault(topic.LanguageCode), &topic)
+	bot.sendBanUser(botChat, i18n.GetOrDefault(bot.LanguageCode), &topic)
+	return topic, nil
+}
+
+func (bot *Bot) Unban(user_id, actor int64) (model.Topic, error) {
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(user_id)
+	if err != nil {
+		return model.Topic{}, err
+	}
+	defer bot.topics.Unlock(user_id)
+
+	var topic model.Topic
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return topic, err
+	}
+
+	if topic.Id == 0 || !topic.IsBan {
+		return topic, nil
+	}
+
+	err = bot.unban(&topic, actor, "")
+	if err != nil {
+		return topic, err
+	}
+
+	botChat := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: bot.GroupId,
+		},
+	}
+
+	bot.sendUnbanUser(botChat, i18n.GetOrDefault(bot.LanguageCode), topic.UserId)
+	return topic, nil
+}
+
+// ExpireChallenges clears the challenges which are not answered within the captcha duration of the bot.
+func (bot *Bot) ExpireChallenges() error {
+	return DB().Model(model.Topic{}).Where("bot_id", bot.Self.ID).Where("verification", model.VerificationNotCompleted).Not("challange_sent", 0).Where(clause.Lte{Column: "challange_sent", Value: time.Now().Add(-bot.captchaDuration()).Unix()}).Updates(map[string]any{
+		"challange_id":   0,
+		"challange_sent": 0,
+		"challange_try":  0,
+		"version":        gorm.Expr("version + 1"),
+	}).Error
+}
+
+// ExpireBan lifts the ban of the user if it is expired, the user and the topic are notified.
+// It reports whether the ban is lifted.
+func (bot *Bot) ExpireBan(user_id int64) (bool, error) {
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(user_id)
+	if err != nil {
+		return false, err
+	}
+	defer bot.topics.Unlock(user_id)
+
+	var topic model.Topic
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return false, err
+	}
+
+	if topic.Id == 0 || !topic.IsBan || topic.BanUntil == 0 || topic.BanUntil > time.Now().Unix() {
+		return false, nil
+	}
+
+	err = bot.unban(&topic, model.AuditActorSystem, "expired")
+	if err != nil {
+		return false, err
+	}
+
+	bot.sendBanExpired(botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	}, i18n.GetOrDefault(topic.LanguageCode))
+
+	// The topic is deleted on ban, notify in General topic
+	bot.sendBanExpiredNotify(botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: bot.GroupId,
+		},
+	}, i18n.GetOrDefault(bot.LanguageCode), topic.UserId)
+	return true, nil
+}
+
+func (bot *Bot) Terminate(user_id, actor int64) error {
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(user_id)
+	if err != nil {
+		return err
+	}
+	defer bot.topics.Unlock(user_id)
+
+	var topic model.Topic
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return err
+	}
+
+	if topic.Id == 0 {
+		return nil
+	}
+
+	err = bot.terminate(&topic, actor)
+	if err != nil {
+		return err
+	}
+
+	if topic.IsBan {
+		return nil
+	}
+
+	userChat := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	}
+
+	bot.sendTerminated(userChat, i18n.GetOrDefault(topic.LanguageCode))
+	return nil
+}
+
+// Reply sends a message to the user as a topic reply, the message is mirrored into the topic.
+func (bot *Bot) Reply(user_id int64, build func(userChat botapi.BaseChat) botapi.Chattable) (model.Msg, error) {
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(user_id)
+	if err != nil {
+		return model.Msg{}, err
+	}
+	defer bot.topics.Unlock(user_id)
+
+	var topic model.Topic
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return model.Msg{}, err
+	}
+
+	if topic.Id == 0 || topic.TopicId == 0 {
+		return model.Msg{}, ErrTopicNotFound
+	}
+
+	if topic.IsBan {
+		return model.Msg{}, ErrUserBanned
+	}
+
+	return bot.reply(&topic, build)
+}
+
+// reply sends a message to the user and mirrors it into the topic, the caller must hold the lock of the user.
+func (bot *Bot) reply(topic *model.Topic, build func(userChat botapi.BaseChat) botapi.Chattable) (model.Msg, error) {
+	userChatConfig := botapi.ChatConfig{
+		ChatID: topic.UserId,
+	}
+
+	message, err := bot.Send(build(botapi.BaseChat{
+		ChatConfig: userChatConfig,
+	}))
+	if err != nil {
+		if err, ok := err.(*botapi.Error); ok && isBlocked(err) {
+			bot.Request(botapi.DeleteForumTopicConfig{
+				BaseForum: botapi.BaseForum{
+					ChatConfig: botapi.ChatConfig{
+						ChatID: bot.GroupId,
+					},
+					MessageThreadID: topic.TopicId,
+				},
+			})
+			terminateTopic(topic)
+			bot.audit(model.AuditActorSystem, topic.UserId, model.AuditBlocked, "")
+			bot.sendBlocked(botapi.BaseChat{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: bot.GroupId,
+				},
+			}, i18n.GetOrDefault(bot.LanguageCode), topic.UserId)
+			bot.publish(events.UserBlocked, topic, nil)
+		}
+
+		return model.Msg{}, err
+	}
+
+	if topic.AwaySent {
+		topic.AwaySent = false
+		err = saveTopic(topic)
+		if err != nil {
+			clog.Errorf("[Bot %d] failed to reset the away reply of user %d, error: %s", bot.Self.ID, topic.UserId, err)
+		}
+	}
+
+	topicMessage, err := bot.Send(botapi.CopyMessageConfig{
+		BaseChat: botapi.BaseChat{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+			MessageThreadID: topic.TopicId,
+		},
+		FromChat:  userChatConfig,
+		MessageID: message.MessageID,
+	})
+	if err != nil {
+		return model.Msg{}, err
+	}
+
+	msg := model.Msg{
+		BotId:      bot.Self.ID,
+		TopicId:    topic.Id,
+		UserMsgId:  message.MessageID,
+		TopicMsgId: topicMessage.MessageID,
+	}
+
+	err = DB().Create(&msg).Error
+	if err != nil {
+		return msg, err
+	}
+
+	bot.archive(model.DirectionOutgoing, topic, []*botapi.Message{&message}, []model.Msg{msg})
+	bot.publish(events.MessageReplied, topic, map[string]any{
+		"user_msg_id":  msg.UserMsgId,
+		"topic_msg_id": msg.TopicMsgId,
+		"message":      &message,
+	})
+	return msg, nil
+}
@@ -0,0 +1,293 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"Topicgram/services/cluster"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const (
+	APPEAL_COOLDOWN = 24 * time.Hour
+
+	appealsTopicSetting = "appeals_topic"
+
+	appealCallbackPrefix  = "appeal:"
+	appealCallbackApprove = "approve"
+	appealCallbackReject  = "reject"
+)
+
+func (bot *Bot) appealCooldown() time.Duration {
+	if bot.Appeal.Cooldown <= 0 {
+		return APPEAL_COOLDOWN
+	}
+	return time.Duration(bot.Appeal.Cooldown) * time.Second
+}
+
+// appealsTopic returns the forum thread of appeals, it is created on first use.
+func (bot *Bot) appealsTopic(renew bool) (int, error) {
+	// Not a topic lock, the caller holds the lock of the user
+	bot.appeals.Lock()
+	defer bot.appeals.Unlock()
+
+	if cluster.Enabled() {
+		unlock, err := cluster.Lock(fmt.Sprintf("appeals:%d", bot.Self.ID))
+		if err != nil {
+			clog.Errorf("[Bot %d] failed to acquire lock of appeals topic, error: %s", bot.Self.ID, err)
+		} else {
+			defer unlock()
+		}
+	}
+
+	var setting model.Setting
+	err := DB().Where("bot_id", bot.Self.ID).Where("key", appealsTopicSetting).Find(&setting).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if !renew && setting.Value != "" {
+		thread_id, err := strconv.Atoi(setting.Value)
+		if err == nil {
+			return thread_id, nil
+		}
+	}
+
+	topic, err := bot.Send(botapi.CreateForumTopicConfig{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: bot.GroupId,
+		},
+		Name: i18n.GetOrDefault(bot.LanguageCode).AppealsTopicName(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	setting = model.Setting{
+		BotId: bot.Self.ID,
+		Key:   appealsTopicSetting,
+		Value: strconv.Itoa(topic.MessageThreadID),
+	}
+	err = DB().Save(&setting).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return topic.MessageThreadID, nil
+}
+
+func appealMarkup(translator i18n.Translator, appeal_id int64) botapi.InlineKeyboardMarkup {
+	id := strconv.FormatInt(appeal_id, 10)
+	return botapi.NewInlineKeyboardMarkup(botapi.NewInlineKeyboardRow(
+		botapi.NewInlineKeyboardButtonData(translator.AppealApprove(), appealCallbackPrefix+appealCallbackApprove+":"+id),
+		botapi.NewInlineKeyboardButtonData(translator.AppealReject(), appealCallbackPrefix+appealCallbackReject+":"+id),
+	))
+}
+
+// handleAppeal posts the appeal of the banned user to the appeals topic, the caller must hold the lock of the user.
+func (bot *Bot) handleAppeal(currentChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic, msg *botapi.Message, content string) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		bot.sendCommandUsageAppeal(currentChat, translator)
+		return
+	}
+
+	var last model.Appeal
+	err := DB().Where("bot_id", bot.Self.ID).Where("user_id", topic.UserId).Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	if last.Id != 0 {
+		wait := time.Until(last.CreatedAt.Add(bot.appealCooldown()))
+		if wait > 0 {
+			bot.sendAppealCooldown(currentChat, translator, wait)
+			return
+		}
+	}
+
+	appeal := model.Appeal{
+		BotId:        bot.Self.ID,
+		UserId:       topic.UserId,
+		UserName:     strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName),
+		LanguageCode: msg.From.LanguageCode,
+		Content:      content,
+	}
+	err = DB().Create(&appeal).Error
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	botTranslator := i18n.GetOrDefault(bot.LanguageCode)
+
+	var post botapi.Message
+	for renew := range 2 {
+		var thread_id int
+		thread_id, err = bot.appealsTopic(renew == 1)
+		if err != nil {
+			break
+		}
+
+		text, entities := botTranslator.Appeal(&appeal)
+		post, err = bot.Send(botapi.MessageConfig{
+			BaseChat: botapi.BaseChat{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: bot.GroupId,
+				},
+				MessageThreadID: thread_id,
+				ReplyMarkup:     appealMarkup(botTranslator, appeal.Id),
+			},
+			Text:     text,
+			Entities: entities,
+		})
+
+		// The appeals topic is deleted, create a new one
+		if err, ok := err.(*botapi.Error); ok && isThreadNotFound(err) {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		DB().Delete(&appeal)
+		bot.sendError(currentChat, translator)
+		return
+	}
+
+	appeal.MessageId = post.MessageID
+	err = DB().Model(&appeal).Update("message_id", appeal.MessageId).Error
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	bot.sendAppealSent(currentChat, translator)
+}
+
+// handleAppealCallback resolves the appeal by the Approve / Reject buttons in the appeals topic.
+func (bot *Bot) handleAppealCallback(callback *botapi.CallbackQuery, data string) {
+	translator := i18n.GetOrDefault(callback.From.LanguageCode)
+
+	action, id, _ := strings.Cut(data, ":")
+	appeal_id, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || (action != appealCallbackApprove && action != appealCallbackReject) {
+		bot.Request(botapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	if bot.roleOf(callback.From.ID) < model.RoleModerator {
+		text, _ := translator.Error_PermissionDenied()
+		bot.Request(botapi.NewCallbackWithAlert(callback.ID, text))
+		return
+	}
+
+	var appeal model.Appeal
+	err = DB().Where("bot_id", bot.Self.ID).Where("id", appeal_id).Find(&appeal).Error
+	if err != nil || appeal.Id == 0 {
+		text, _ := translator.Error()
+		bot.Request(botapi.NewCallbackWithAlert(callback.ID, text))
+		return
+	}
+
+	status := model.AppealRejected
+	if action == appealCallbackApprove {
+		status = model.AppealApproved
+	}
+
+	// Claim the appeal, it may be resolved by another administrator concurrently
+	result := DB().Model(&appeal).Where("status", model.AppealPending).Updates(map[string]any{
+		"status":      status,
+		"resolved_by": callback.From.ID,
+		"resolved_at": time.Now().Unix(),
+	})
+	if result.Error != nil {
+		text, _ := translator.Error_Database()
+		bot.Request(botapi.NewCallbackWithAlert(callback.ID, text))
+		return
+	}
+
+	if result.RowsAffected != 0 && status == model.AppealApproved {
+		_, err = bot.Unban(appeal.UserId, callback.From.ID)
+		if err != nil {
+			DB().Model(&appeal).Updates(map[string]any{
+				"status":      model.AppealPending,
+				"resolved_by": 0,
+				"resolved_at": 0,
+			})
+
+			text, _ := translator.Error_Database()
+			bot.Request(botapi.NewCallbackWithAlert(callback.ID, text))
+			return
+		}
+	}
+
+	bot.Request(botapi.NewCallback(callback.ID, ""))
+
+	// Reload for the resolver and the final status
+	err = DB().Where("id", appeal.Id).Find(&appeal).Error
+	if err != nil {
+		return
+	}
+
+	if callback.Message != nil {
+		text, entities := i18n.GetOrDefault(bot.LanguageCode).Appeal(&appeal)
+		bot.Send(botapi.EditMessageTextConfig{
+			BaseEdit: botapi.BaseEdit{
+				BaseChatMessage: botapi.BaseChatMessage{
+					ChatConfig: botapi.ChatConfig{
+						ChatID: callback.Message.Chat.ID,
+					},
+					MessageID: callback.Message.MessageID,
+				},
+			},
+			Text:     text,
+			Entities: entities,
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	auditAction := model.AuditAppealRejected
+	if appeal.Status == model.AppealApproved {
+		auditAction = model.AuditAppealApproved
+	}
+	bot.audit(callback.From.ID, appeal.UserId, auditAction, appeal.Content)
+
+	userChat := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: appeal.UserId,
+		},
+	}
+	userTranslator := i18n.GetOrDefault(appeal.LanguageCode)
+
+	switch appeal.Status {
+	case model.AppealApproved:
+		bot.sendAppealApproved(userChat, userTranslator)
+	case model.AppealRejected:
+		bot.sendAppealRejected(userChat, userTranslator)
+	}
+}
+
+// handleTopicCallback dispatches the callback queries in the forum group.
+func (bot *Bot) handleTopicCallback(callback *botapi.CallbackQuery) {
+	if data, ok := strings.CutPrefix(callback.Data, appealCallbackPrefix); ok {
+		bot.handleAppealCallback(callback, data)
+		return
+	}
+
+	if data, ok := strings.CutPrefix(callback.Data, senderCallbackPrefix); ok {
+		bot.handleSenderCallback(callback, data)
+		return
+	}
+
+	bot.Request(botapi.NewCallback(callback.ID, ""))
+}
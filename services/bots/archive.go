@@ -0,0 +1,152 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"encoding/json"
+	"strings"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const searchLimit = 10
+
+// archive keeps the content of the relayed messages if archive is enabled.
+func (bot *Bot) archive(direction model.Direction, topic *model.Topic, messages []*botapi.Message, msgs []model.Msg) {
+	if !bot.Archive {
+		return
+	}
+
+	archives := make([]model.Archive, 0, len(messages))
+	for i, msg := range messages {
+		if i >= len(msgs) {
+			break
+		}
+
+		archives = append(archives, newArchive(direction, topic, msg, msgs[i]))
+	}
+
+	if len(archives) == 0 {
+		return
+	}
+
+	err := DB().Create(archives).Error
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to archive messages, error: %s", bot.Self.ID, err)
+	}
+}
+
+// archiveEdit keeps the new content of an edited message, the edit is linked to the archived message.
+func (bot *Bot) archiveEdit(direction model.Direction, topic *model.Topic, msg *botapi.Message, m model.Msg) {
+	if !bot.Archive {
+		return
+	}
+
+	var original model.Archive
+	err := DB().Where("bot_id", bot.Self.ID).Where("topic_id", topic.Id).Where("user_msg_id", m.UserMsgId).Where("edit_of", 0).Find(&original).Error
+	if err != nil || original.Id == 0 {
+		return
+	}
+
+	archive := newArchive(direction, topic, msg, m)
+	archive.EditOf = original.Id
+
+	err = DB().Create(&archive).Error
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to archive edit, error: %s", bot.Self.ID, err)
+	}
+}
+
+func newArchive(direction model.Direction, topic *model.Topic, msg *botapi.Message, m model.Msg) model.Archive {
+	archive := model.Archive{
+		BotId:      topic.BotId,
+		UserId:     topic.UserId,
+		TopicId:    topic.Id,
+		ThreadId:   topic.TopicId,
+		Direction:  direction,
+		UserMsgId:  m.UserMsgId,
+		TopicMsgId: m.TopicMsgId,
+		Content:    msg.Text,
+		Entities:   "[]",
+		CreatedAt:  time.Unix(int64(msg.Date), 0),
+	}
+
+	if msg.EditDate != 0 {
+		archive.CreatedAt = time.Unix(int64(msg.EditDate), 0)
+	}
+
+	if msg.From != nil {
+		archive.SenderId = msg.From.ID
+		archive.SenderName = strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName)
+	}
+
+	entities := msg.Entities
+	if msg.Text == "" {
+		archive.Content = msg.Caption
+		entities = msg.CaptionEntities
+	}
+
+	if len(entities) != 0 {
+		data, err := json.Marshal(entities)
+		if err == nil {
+			archive.Entities = string(data)
+		}
+	}
+
+	archive.MediaType, archive.FileId = messageMedia(msg)
+	return archive
+}
+
+func messageMedia(msg *botapi.Message) (string, string) {
+	switch {
+	case msg.Animation != nil:
+		return "animation", msg.Animation.FileID
+	case msg.Audio != nil:
+		return "audio", msg.Audio.FileID
+	case msg.Document != nil:
+		return "document", msg.Document.FileID
+	case msg.Photo != nil:
+		var media botapi.PhotoSize
+		for _, photo := range msg.Photo {
+			if photo.FileSize > media.FileSize {
+				media = photo
+			}
+		}
+
+		return "photo", media.FileID
+	case msg.Sticker != nil:
+		return "sticker", msg.Sticker.FileID
+	case msg.Video != nil:
+		return "video", msg.Video.FileID
+	case msg.VideoNote != nil:
+		return "video_note", msg.VideoNote.FileID
+	case msg.Voice != nil:
+		return "voice", msg.Voice.FileID
+	default:
+		return "", ""
+	}
+}
+
+// searchArchives finds the archived messages by content, native full-text search is used on MySQL and Postgres.
+func (bot *Bot) searchArchives(query string) ([]model.Archive, error) {
+	db := DB().Where("bot_id", bot.Self.ID)
+
+	switch DB().Dialector.Name() {
+	case "mysql":
+		db = db.Where("MATCH (content) AGAINST (? IN BOOLEAN MODE)", query)
+	case "postgres":
+		db = db.Where("to_tsvector('simple', content) @@ plainto_tsquery('simple', ?)", query)
+	default:
+		db = db.Where("content LIKE ? ESCAPE '!'", "%"+escapeLike(query)+"%")
+	}
+
+	var archives []model.Archive
+	err := db.Order("id DESC").Limit(searchLimit).Find(&archives).Error
+	return archives, err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,36 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const AUDIT_LIMIT = 20
+
+// audit records the action in the audit log, failures are only logged.
+func (bot *Bot) audit(actor, target int64, action model.AuditAction, reason string) {
+	err := DB().Create(&model.Audit{
+		BotId:    bot.Self.ID,
+		ActorId:  actor,
+		TargetId: target,
+		Action:   action,
+		Reason:   reason,
+	}).Error
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to write audit log, action: %s, error: %s", bot.Self.ID, action, err)
+	}
+}
+
+// auditLog returns the latest entries of the audit log, target 0 for all users.
+func (bot *Bot) auditLog(target int64) ([]model.Audit, error) {
+	query := DB().Where("bot_id", bot.Self.ID)
+	if target != 0 {
+		query = query.Where("target_id", target)
+	}
+
+	var audits []model.Audit
+	err := query.Order("id DESC").Limit(AUDIT_LIMIT).Find(&audits).Error
+	return audits, err
+}
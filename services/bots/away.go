@@ -0,0 +1,142 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"Topicgram/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const (
+	awaySetting = "away"
+
+	awayOn  = "on"
+	awayOff = "off"
+)
+
+// isAway reports whether the bot is away at the time, next is the next opening time, zero if unknown.
+// The /away override takes precedence over the schedule.
+func (bot *Bot) isAway(now time.Time) (bool, time.Time) {
+	var setting model.Setting
+	err := DB().Where("bot_id", bot.Self.ID).Where("key", awaySetting).Find(&setting).Error
+	if err != nil {
+		return false, time.Time{}
+	}
+
+	switch setting.Value {
+	case awayOn:
+		return true, time.Time{}
+	case awayOff:
+		return false, time.Time{}
+	case "":
+	default:
+		// away until the time, the schedule applies after it
+		until, err := strconv.ParseInt(setting.Value, 10, 64)
+		if err == nil && now.Before(time.Unix(until, 0)) {
+			return true, bot.Schedule.NextOpen(time.Unix(until, 0))
+		}
+	}
+
+	if bot.Schedule.IsOpen(now) {
+		return false, time.Time{}
+	}
+	return true, bot.Schedule.NextOpen(now)
+}
+
+// replyAway sends the away reply once until an administrator replies, it is sent only after the message is relayed.
+func (bot *Bot) replyAway(baseChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic) {
+	if topic.AwaySent {
+		return
+	}
+
+	away, next := bot.isAway(time.Now())
+	if !away {
+		return
+	}
+
+	topic.AwaySent = true
+	err := saveTopic(topic)
+	if err != nil {
+		topic.AwaySent = false
+		clog.Errorf("[Bot %d] failed to save the away reply of user %d, error: %s", bot.Self.ID, topic.UserId, err)
+		return
+	}
+
+	bot.sendAway(baseChat, translator, next)
+}
+
+// parseAwayUntil parses the time of /away until, a duration like 2h or a time like 15:04, 2006-01-02 or 2006-01-02 15:04 in the time zone of the schedule.
+func (bot *Bot) parseAwayUntil(value string, now time.Time) (time.Time, bool) {
+	if duration, ok := utils.ParseDuration(value); ok {
+		return now.Add(duration), true
+	}
+
+	location := bot.Schedule.Location()
+	for _, layout := range []string{"2006-01-02 15:04", time.DateOnly} {
+		until, err := time.ParseInLocation(layout, value, location)
+		if err == nil {
+			return until, until.After(now)
+		}
+	}
+
+	clock, err := time.ParseInLocation("15:04", value, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(location)
+	until := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+	if !until.After(now) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// handleAwayCommand overrides the schedule: /away on, /away off, /away until <time>, /away auto to follow the schedule again.
+func (bot *Bot) handleAwayCommand(currentChat botapi.BaseChat, translator i18n.Translator, args string) {
+	mode, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+
+	now := time.Now()
+	setting := model.Setting{
+		BotId: bot.Self.ID,
+		Key:   awaySetting,
+	}
+
+	switch mode {
+	case awayOn, awayOff:
+		setting.Value = mode
+	case "until":
+		until, ok := bot.parseAwayUntil(strings.TrimSpace(value), now)
+		if !ok {
+			bot.sendCommandUsageAway(currentChat, translator)
+			return
+		}
+		setting.Value = strconv.FormatInt(until.Unix(), 10)
+	case "auto":
+		err := DB().Where("bot_id", bot.Self.ID).Where("key", awaySetting).Delete(&model.Setting{}).Error
+		if err != nil {
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		bot.sendSuccess(currentChat, translator)
+		return
+	default:
+		bot.sendCommandUsageAway(currentChat, translator)
+		return
+	}
+
+	err := DB().Save(&setting).Error
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	bot.sendSuccess(currentChat, translator)
+}
@@ -4,9 +4,13 @@ import (
 	. "Topicgram/database"
 	"Topicgram/i18n"
 	"Topicgram/model"
-	"Topicgram/services/captcha"
+	"Topicgram/services/events"
+	"Topicgram/services/transcript"
+	"context"
+	"errors"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	botapi "github.com/OvyFlash/telegram-bot-api"
@@ -16,6 +20,13 @@ import (
 type Bot struct {
 	*model.BotConfig
 	*BotAPI
+
+	secretToken string
+	stopPolling context.CancelFunc
+	managed     bool // loaded by manager
+
+	roles   sync.Map   // user_id -> cachedRole
+	appeals sync.Mutex // guards the creation of the appeals topic
 }
 
 func Recover() {
@@ -29,6 +40,10 @@ func Recover() {
 func (bot *Bot) handleUpdate(update *botapi.Update) {
 	defer Recover()
 
+	if update.Message != nil {
+		defer bot.pendingMediaGroups.Delete(update.Message)
+	}
+
 	chat := update.FromChat()
 	if chat == nil {
 		return
@@ -54,6 +69,8 @@ func (bot *Bot) handleUpdate(update *botapi.Update) {
 		}
 
 		switch {
+		case update.CallbackQuery != nil:
+			bot.handleTopicCallback(update.CallbackQuery)
 		case update.Message != nil:
 			bot.handleTopicNewMessage(update.Message)
 		case update.EditedMessage != nil:
@@ -66,6 +83,11 @@ func (bot *Bot) handleUpdate(update *botapi.Update) {
 		case update.MyChatMember != nil:
 			bot.handleMyChatMember(update.MyChatMember)
 		case update.CallbackQuery != nil:
+			if data, ok := strings.CutPrefix(update.CallbackQuery.Data, faqCallbackPrefix); ok {
+				bot.handleFAQCallback(update.CallbackQuery, data)
+				return
+			}
+
 			bot.handleUserVerification(update.CallbackQuery)
 		case update.Message != nil:
 			bot.handleUserNewMessage(update.Message)
@@ -89,11 +111,14 @@ func (bot *Bot) handleMyChatMember(chatMember *botapi.ChatMemberUpdated) {
 	bot.bot.RLock()
 	defer bot.bot.RUnlock()
 
-	bot.topic.Lock()
-	defer bot.topic.Unlock()
+	err := bot.topics.Lock(chatMember.From.ID)
+	if err != nil {
+		return
+	}
+	defer bot.topics.Unlock(chatMember.From.ID)
 
 	var topic model.Topic
-	err := DB().Where("user_id", chatMember.From.ID).Find(&topic).Error
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", chatMember.From.ID).Find(&topic).Error
 	if err != nil {
 		return
 	}
@@ -122,7 +147,9 @@ func (bot *Bot) handleMyChatMember(chatMember *botapi.ChatMemberUpdated) {
 		BaseForum: botTopic,
 	})
 	terminateTopic(&topic)
+	bot.audit(topic.UserId, topic.UserId, model.AuditBlocked, "")
 	bot.sendBlocked(botChat, translator, topic.UserId)
+	bot.publish(events.UserBlocked, &topic, nil)
 }
 
 func (bot *Bot) handleUserVerification(callback *botapi.CallbackQuery) {
@@ -158,11 +185,15 @@ func (bot *Bot) handleUserVerification(callback *botapi.CallbackQuery) {
 	bot.bot.RLock()
 	defer bot.bot.RUnlock()
 
-	bot.topic.Lock()
-	defer bot.topic.Unlock()
+	err := bot.topics.Lock(callback.From.ID)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+	defer bot.topics.Unlock(callback.From.ID)
 
 	var topic model.Topic
-	err := DB().Where("user_id", callback.From.ID).Find(&topic).Error
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", callback.From.ID).Find(&topic).Error
 	if err != nil {
 		bot.sendDatabaseError(currentChat, translator, err)
 		return
@@ -179,12 +210,12 @@ func (bot *Bot) handleUserVerification(callback *botapi.CallbackQuery) {
 		bot.Request(botapi.DeleteMessageConfig{
 			BaseChatMessage: currentMessage,
 		})
-		bot.sendBanned(currentChat, translator)
+		bot.sendBanned(currentChat, translator, &topic)
 		return
 	}
 
 	challangeId := topic.ChallangeId
-	notAfter := time.Unix(topic.ChallangeSent, 0).Add(CAPTCHA_DURATION)
+	notAfter := time.Unix(topic.ChallangeSent, 0).Add(bot.captchaDuration())
 
 	topic.ChallangeSent = 0
 	topic.ChallangeId = 0
@@ -194,50 +225,24 @@ func (bot *Bot) handleUserVerification(callback *botapi.CallbackQuery) {
 		return
 	}
 
-	if time.Now().After(notAfter) {
-		bot.Request(botapi.DeleteMessageConfig{
-			BaseChatMessage: currentMessage,
-		})
-		bot.sendCaptchaFailed(currentChat, translator)
-		return
-	}
-
-	if !captcha.CheckMath(bot.Token, challangeId, callback.Data) {
-		bot.Request(botapi.DeleteMessageConfig{
-			BaseChatMessage: currentMessage,
-		})
-		bot.sendCaptchaFailed(currentChat, translator)
-		return
-	}
-
-	topic.Verification = model.VerificationCompleted
-	err = saveTopic(&topic)
-	if err != nil {
-		bot.sendDatabaseError(currentChat, translator, err)
-		return
-	}
-
 	bot.Request(botapi.DeleteMessageConfig{
 		BaseChatMessage: currentMessage,
 	})
-	bot.sendCaptchaCompleted(currentChat, translator)
 
-	if topic.TopicId == 0 {
+	if time.Now().After(notAfter) || !bot.challenge().Verify(bot.Token, challangeId, callback.Data) {
+		bot.failCaptcha(currentChat, translator, &topic)
 		return
 	}
 
-	botTranslator := i18n.GetOrDefault(bot.LanguageCode)
-	botChatConfig := botapi.ChatConfig{
-		ChatID: bot.GroupId,
-	}
-	botTopic := botapi.BaseChat{
-		ChatConfig:      botChatConfig,
-		MessageThreadID: topic.TopicId,
-	}
-	bot.sendCaptchaCompletedNotify(botTopic, botTranslator)
+	bot.completeCaptcha(currentChat, translator, &topic)
 }
 
 func (bot *Bot) handleUserNewMessage(msg *botapi.Message) {
+	bot.handleUserMessage(msg, false)
+}
+
+// handleUserMessage forwards the message of the user to the topic, handoff skips the FAQ as the user asked for a human.
+func (bot *Bot) handleUserMessage(msg *botapi.Message, handoff bool) {
 	translator := i18n.GetOrDefault(msg.From.LanguageCode)
 
 	currentChatConfig := botapi.ChatConfig{
@@ -250,10 +255,7 @@ func (bot *Bot) handleUserNewMessage(msg *botapi.Message) {
 		},
 	}
 
-	mediaGroup, skip := bot.getMediaGroup(msg)
-	if skip {
-		return
-	}
+	mediaGroup := bot.getMediaGroup(msg)
 
 	switch {
 	case isServiceMessage(msg):
@@ -289,11 +291,15 @@ func (bot *Bot) handleUserNewMessage(msg *botapi.Message) {
 	bot.bot.RLock()
 	defer bot.bot.RUnlock()
 
-	bot.topic.Lock()
-	defer bot.topic.Unlock()
+	err := bot.topics.Lock(msg.From.ID)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+	defer bot.topics.Unlock(msg.From.ID)
 
 	var topic model.Topic
-	err := DB().Where("user_id", msg.From.ID).Find(&topic).Error
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", msg.From.ID).Find(&topic).Error
 	if err != nil {
 		bot.sendDatabaseError(currentChat, translator, err)
 		return
@@ -307,46 +313,73 @@ func (bot *Bot) handleUserNewMessage(msg *botapi.Message) {
 		ChatConfig: botChatConfig,
 	}
 	botTopic := botapi.BaseChat{
-		ChatConfig:      botChatConfig,
-		MessageThreadID: topic.TopicId,
+		ChatConfig:          botChatConfig,
+		MessageThreadID:     topic.TopicId,
+		DisableNotification: topic.IsMuted,
+	}
+
+	if topic.IsBan {
+		if command, args, _ := strings.Cut(msg.Text, " "); command == "/appeal" || command == "/appeal@"+bot.Self.UserName {
+			bot.handleAppeal(currentChat, translator, &topic, msg, args)
+			return
+		}
+
+		bot.sendBanned(currentChat, translator, &topic)
+		return
 	}
 
 	switch topic.Verification {
 	case model.VerificationNotSent:
 		if bot.shouldSendCaptcha(msg) {
-			description, replyMarkup := bot.captchaMath(translator, &topic)
-			err := bot.sendCaptcha(currentChat, translator, description, replyMarkup)
-			if err != nil {
+			bot.issueCaptcha(currentChat, translator, &topic, msg)
+			return
+		}
+	case model.VerificationNotCompleted:
+		// Failed or expired, a new challange is issued after the cooldown
+		if topic.ChallangeId == 0 {
+			wait := time.Until(time.Unix(topic.CaptchaRetryAt, 0))
+			if wait > 0 {
+				bot.sendCaptchaCooldown(currentChat, translator, wait)
 				return
 			}
 
-			topic.UserId = msg.From.ID
-			topic.Verification = model.VerificationNotCompleted
-			topic.ChallangeSent = time.Now().Unix()
-			topic.LanguageCode = msg.From.LanguageCode
-
-			err = saveTopic(&topic)
-			if err != nil {
-				bot.sendDatabaseError(currentChat, translator, err)
-				return
-			}
+			bot.issueCaptcha(currentChat, translator, &topic, msg)
+			return
+		}
 
-			if topic.TopicId != 0 {
-				bot.sendCaptchaNotify(botTopic, botTranslator)
-			}
+		// Typed answer is not forwarded
+		if bot.isTypedCaptcha() && topic.ChallangeId != 0 && topic.ChallangeSent != 0 {
+			bot.handleCaptchaAnswer(currentChat, translator, &topic, msg)
 			return
 		}
-	case model.VerificationNotCompleted:
+
 		bot.sendCaptchaNotCompleted(currentChat, translator)
 		return
 	}
 
+	if !handoff {
+		if answer, ok := bot.faqAnswer(msg); ok {
+			bot.sendFAQAnswer(currentChat, translator, answer)
+			return
+		}
+	}
+
+	// The topic is changed concurrently, continue with the stored one
+	reloadTopic := func() error {
+		topic = model.Topic{}
+		err := DB().Where("bot_id", bot.Self.ID).Where("user_id", msg.From.ID).Find(&topic).Error
+		botTopic.MessageThreadID = topic.TopicId
+		botTopic.DisableNotification = topic.IsMuted
+		return err
+	}
+
 retry:
 	switch {
 	case topic.IsBan:
-		bot.sendBanned(currentChat, translator)
+		bot.sendBanned(currentChat, translator, &topic)
 		return
 	case topic.Id == 0:
+		topic.BotId = bot.Self.ID
 		topic.UserId = msg.From.ID
 		topic.LanguageCode = msg.From.LanguageCode
 		fallthrough
@@ -363,9 +396,31 @@ retry:
 
 		botTopic.MessageThreadID = createdTopic.MessageThreadID
 		topic.TopicId = createdTopic.MessageThreadID
-		saveTopic(&topic)
+		topic.State = model.TicketOpen
+		err = saveTopic(&topic)
+		if err != nil {
+			// The created topic is orphaned, the stored one is used on conflict
+			bot.Request(botapi.DeleteForumTopicConfig{
+				BaseForum: botapi.BaseForum{
+					ChatConfig:      botChatConfig,
+					MessageThreadID: createdTopic.MessageThreadID,
+				},
+			})
+			if errors.Is(err, ErrTopicConflict) {
+				err = reloadTopic()
+				if err == nil {
+					goto retry
+				}
+			}
 
-		message, err := bot.sendSender(botTopic, botTranslator, msg.From)
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+		bot.publish(events.TopicCreated, &topic, map[string]any{
+			"user": msg.From,
+		})
+
+		message, err := bot.sendSender(botTopic, botTranslator, msg.From, &topic)
 		if err != nil {
 			if err, ok := err.(*botapi.Error); ok {
 				bot.sendTelegramError(currentChat, err)
@@ -382,6 +437,25 @@ retry:
 			},
 			DisableNotification: true,
 		})
+
+		topic.SenderMsgId = message.MessageID
+		err = saveTopic(&topic)
+		if err != nil {
+			if errors.Is(err, ErrTopicConflict) {
+				err = reloadTopic()
+				if err == nil {
+					goto retry
+				}
+			}
+
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+	}
+
+	// A new message reopens the ticket
+	if topic.State != model.TicketOpen {
+		bot.setTicketState(&topic, model.TicketOpen, msg.From.ID)
 	}
 
 	if msg.HasProtectedContent {
@@ -421,13 +495,15 @@ retry:
 				topic_message_id := messageIds[i].MessageID
 
 				msgs = append(msgs, model.Msg{
+					BotId:      bot.Self.ID,
 					TopicId:    topic.Id,
 					UserMsgId:  msg.MessageID,
 					TopicMsgId: topic_message_id,
 				})
 			}
 
-			DB().Create(msgs)
+			bot.saveMessages(events.MessageReceived, &topic, mediaGroup.Messages, msgs)
+			bot.replyAway(currentChat, translator, &topic)
 			return
 		}
 
@@ -450,11 +526,13 @@ retry:
 			return
 		}
 
-		DB().Create(&model.Msg{
+		bot.saveMessages(events.MessageReceived, &topic, []*botapi.Message{msg}, []model.Msg{{
+			BotId:      bot.Self.ID,
 			TopicId:    topic.Id,
 			UserMsgId:  msg.MessageID,
 			TopicMsgId: message.MessageID,
-		})
+		}})
+		bot.replyAway(currentChat, translator, &topic)
 		return
 	}
 
@@ -508,13 +586,15 @@ retry:
 			topic_message_id := messages[i].MessageID
 
 			msgs = append(msgs, model.Msg{
+				BotId:      bot.Self.ID,
 				TopicId:    topic.Id,
 				UserMsgId:  msg.MessageID,
 				TopicMsgId: topic_message_id,
 			})
 		}
 
-		DB().Create(msgs)
+		bot.saveMessages(events.MessageReceived, &topic, mediaGroup.Messages, msgs)
+		bot.replyAway(currentChat, translator, &topic)
 		return
 	}
 
@@ -537,11 +617,13 @@ retry:
 		return
 	}
 
-	DB().Create(&model.Msg{
+	bot.saveMessages(events.MessageReceived, &topic, []*botapi.Message{msg}, []model.Msg{{
+		BotId:      bot.Self.ID,
 		TopicId:    topic.Id,
 		UserMsgId:  msg.MessageID,
 		TopicMsgId: message.MessageID,
-	})
+	}})
+	bot.replyAway(currentChat, translator, &topic)
 }
 
 func (bot *Bot) handleUserEditMessage(msg *botapi.Message) {
@@ -582,11 +664,15 @@ func (bot *Bot) handleUserEditMessage(msg *botapi.Message) {
 	bot.bot.RLock()
 	defer bot.bot.RUnlock()
 
-	bot.topic.Lock()
-	defer bot.topic.Unlock()
+	err := bot.topics.Lock(msg.From.ID)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+	defer bot.topics.Unlock(msg.From.ID)
 
 	var topic model.Topic
-	err := DB().Where("user_id", msg.From.ID).Find(&topic).Error
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", msg.From.ID).Find(&topic).Error
 	if err != nil {
 		bot.sendDatabaseError(currentChat, translator, err)
 		return
@@ -599,7 +685,7 @@ func (bot *Bot) handleUserEditMessage(msg *botapi.Message) {
 		bot.sendFailedToEdit(currentChat, translator)
 		return
 	case topic.IsBan:
-		bot.sendBanned(currentChat, translator)
+		bot.sendBanned(currentChat, translator, &topic)
 		return
 	}
 
@@ -635,6 +721,7 @@ func (bot *Bot) handleUserEditMessage(msg *botapi.Message) {
 		bot.sendError(currentChat, translator)
 		return
 	}
+	bot.archiveEdit(model.DirectionIncoming, &topic, msg, message)
 }
 
 func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
@@ -681,7 +768,7 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 
 		if msg.MigrateFromChatID == bot.GroupId {
 			bot.GroupId = msg.Chat.ID
-			clog.Infof("[Bot] Group migrated to %d, please update config", msg.Chat.ID)
+			clog.Infof("[Bot %d] Group migrated to %d, please update config", bot.Self.ID, msg.Chat.ID)
 		}
 		return
 	case msg.MigrateToChatID != 0:
@@ -690,7 +777,7 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 
 		if msg.Chat.ID == bot.GroupId {
 			bot.GroupId = msg.MigrateToChatID
-			clog.Infof("[Bot] Group migrated to %d, please update config", msg.MigrateToChatID)
+			clog.Infof("[Bot %d] Group migrated to %d, please update config", bot.Self.ID, msg.MigrateToChatID)
 		}
 		return
 	case msg.ForumTopicClosed != nil:
@@ -708,43 +795,34 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			return
 		}
 
+		// Revert it, the member is not allowed to resolve tickets
+		if !bot.hasRole(msg, model.RoleAgent) {
+			bot.Request(botapi.ReopenForumTopicConfig{
+				BaseForum: currentForum,
+			})
+			return
+		}
+
 		bot.bot.RLock()
 		defer bot.bot.RUnlock()
 
-		bot.topic.Lock()
-		defer bot.topic.Unlock()
-
-		var topic model.Topic
-		err := DB().Where("topic_id", msg.MessageThreadID).Find(&topic).Error
+		topic, unlock, err := bot.findTopicByThread(msg.MessageThreadID)
+		defer unlock()
 		if err != nil {
 			bot.sendDatabaseError(currentTopic, translator, err)
 			return
 		}
 
-		if topic.Id == 0 {
+		if topic.Id == 0 || topic.IsBan {
 			return
 		}
 
-		if topic.IsBan {
-			return
-		}
-
-		topic.IsBan = true
-		err = DB().Save(&topic).Error
+		// Closing the topic resolves the ticket, /ban bans the user
+		err = bot.setTicketState(&topic, model.TicketResolved, msg.From.ID)
 		if err != nil {
 			bot.sendDatabaseError(currentTopic, translator, err)
 			return
 		}
-
-		userTranslator := i18n.GetOrDefault(topic.LanguageCode)
-		userChat := botapi.BaseChat{
-			ChatConfig: botapi.ChatConfig{
-				ChatID: topic.UserId,
-			},
-		}
-
-		bot.sendBanned(userChat, userTranslator)
-		bot.sendBanUser(currentTopic, translator, topic.UserId)
 		return
 
 	case msg.ForumTopicReopened != nil:
@@ -762,14 +840,19 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			return
 		}
 
+		// Revert it, the member is not allowed to reopen tickets
+		if !bot.hasRole(msg, model.RoleAgent) {
+			bot.Request(botapi.CloseForumTopicConfig{
+				BaseForum: currentForum,
+			})
+			return
+		}
+
 		bot.bot.RLock()
 		defer bot.bot.RUnlock()
 
-		bot.topic.Lock()
-		defer bot.topic.Unlock()
-
-		var topic model.Topic
-		err := DB().Where("topic_id", msg.MessageThreadID).Find(&topic).Error
+		topic, unlock, err := bot.findTopicByThread(msg.MessageThreadID)
+		defer unlock()
 		if err != nil {
 			bot.sendDatabaseError(currentTopic, translator, err)
 			return
@@ -780,18 +863,32 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		}
 
 		if !topic.IsBan {
+			err = bot.setTicketState(&topic, model.TicketOpen, msg.From.ID)
+			if err != nil {
+				bot.sendDatabaseError(currentTopic, translator, err)
+			}
+			return
+		}
+
+		// Topics closed before the ticket states were bans, only a moderator may lift them
+		if !bot.hasRole(msg, model.RoleModerator) {
+			bot.Request(botapi.CloseForumTopicConfig{
+				BaseForum: currentForum,
+			})
 			return
 		}
 
 		topic.IsBan = false
 
-		err = DB().Save(&topic).Error
+		err = saveTopic(&topic)
 		if err != nil {
 			bot.sendDatabaseError(currentTopic, translator, err)
 			return
 		}
+		bot.audit(msg.From.ID, topic.UserId, model.AuditTopicReopened, "")
 
 		bot.sendUnbanUser(currentTopic, translator, topic.UserId)
+		bot.publish(events.UserUnbanned, &topic, nil)
 		return
 	case isAllowedMessage(msg):
 		// Ignored self message
@@ -815,49 +912,58 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		}
 
 		command, args, _ := strings.Cut(msg.Text, " ")
+		if !bot.canRunCommand(msg, command) {
+			bot.sendPermissionDenied(currentChat, translator)
+			return
+		}
+
 		switch command {
 		case "/ban", "/ban@" + bot.Self.UserName:
-			user_id, err := strconv.ParseInt(args, 10, 64)
+			fields := strings.Fields(args)
+			if len(fields) < 1 {
+				bot.sendCommandUsageBan(currentChat, translator)
+				return
+			}
+
+			user_id, err := strconv.ParseInt(fields[0], 10, 64)
 			if err != nil {
 				bot.sendCommandUsageBan(currentChat, translator)
 				return
 			}
+			duration, reason := parseBanArgs(fields[1:])
 
 			bot.bot.RLock()
 			defer bot.bot.RUnlock()
 
-			bot.topic.Lock()
-			defer bot.topic.Unlock()
+			err = bot.topics.Lock(user_id)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+			defer bot.topics.Unlock(user_id)
 
 			var topic model.Topic
-			err = DB().Where("user_id", user_id).Find(&topic).Error
+			err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
 			}
 
 			if topic.IsBan {
-				bot.sendBanUser(currentChat, translator, user_id)
+				bot.sendBanUser(currentChat, translator, &topic)
 				return
 			}
 
-			if topic.TopicId != 0 {
-				bot.Request(botapi.DeleteForumTopicConfig{
-					BaseForum: currentForum,
-				})
-				DB().Model(model.Msg{}).Where("topic_id", topic.Id).Delete(nil)
-				topic.TopicId = 0
-			}
-
+			topic.BotId = bot.Self.ID
 			topic.UserId = user_id
 
-			err = banTopic(&topic)
+			err = bot.ban(&topic, msg.From.ID, duration, reason)
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
 			}
 
-			bot.sendBanUser(currentChat, translator, user_id)
+			bot.sendBanUser(currentChat, translator, &topic)
 			return
 
 		case "/unban", "/unban@" + bot.Self.UserName:
@@ -870,11 +976,15 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			bot.bot.RLock()
 			defer bot.bot.RUnlock()
 
-			bot.topic.Lock()
-			defer bot.topic.Unlock()
+			err = bot.topics.Lock(user_id)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+			defer bot.topics.Unlock(user_id)
 
 			var topic model.Topic
-			err = DB().Where("user_id", user_id).Find(&topic).Error
+			err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
@@ -885,13 +995,7 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 				return
 			}
 
-			if topic.TopicId != 0 {
-				bot.Request(botapi.ReopenForumTopicConfig{
-					BaseForum: currentForum,
-				})
-			}
-
-			err = unbanTopic(&topic)
+			err = bot.unban(&topic, msg.From.ID, "")
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
@@ -910,11 +1014,15 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			bot.bot.RLock()
 			defer bot.bot.RUnlock()
 
-			bot.topic.Lock()
-			defer bot.topic.Unlock()
+			err = bot.topics.Lock(user_id)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+			defer bot.topics.Unlock(user_id)
 
 			var topic model.Topic
-			err = DB().Where("user_id", user_id).Find(&topic).Error
+			err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
@@ -925,20 +1033,112 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 				return
 			}
 
-			if topic.TopicId != 0 {
-				bot.Request(botapi.DeleteForumTopicConfig{
-					BaseForum: currentForum,
-				})
+			err = bot.terminate(&topic, msg.From.ID)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+			bot.sendSuccess(currentChat, translator)
+			return
+
+		case "/search", "/search@" + bot.Self.UserName:
+			query := strings.TrimSpace(args)
+			if query == "" {
+				bot.sendCommandUsageSearch(currentChat, translator)
+				return
 			}
 
-			err = terminateTopic(&topic)
+			archives, err := bot.searchArchives(query)
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
 			}
 
-			DB().Model(model.Msg{}).Where("topic_id", topic.Id).Delete(nil)
-			bot.sendSuccess(currentChat, translator)
+			bot.sendSearchResults(currentChat, translator, bot.GroupId, archives)
+			return
+
+		case "/audit", "/audit@" + bot.Self.UserName:
+			var target int64
+			if args = strings.TrimSpace(args); args != "" {
+				user_id, err := strconv.ParseInt(args, 10, 64)
+				if err != nil {
+					bot.sendCommandUsageAudit(currentChat, translator)
+					return
+				}
+				target = user_id
+			}
+
+			audits, err := bot.auditLog(target)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+			bot.sendAuditLog(currentChat, translator, audits)
+			return
+
+		case "/snippet", "/snippet@" + bot.Self.UserName:
+			bot.handleSnippetCommand(currentChat, translator, msg, args)
+			return
+
+		case "/away", "/away@" + bot.Self.UserName:
+			bot.handleAwayCommand(currentChat, translator, args)
+			return
+
+		case "/tickets", "/tickets@" + bot.Self.UserName:
+			var pending bool
+			switch strings.TrimSpace(args) {
+			case "", "open":
+			case "pending":
+				pending = true
+			default:
+				bot.sendCommandUsageTickets(currentChat, translator)
+				return
+			}
+
+			topics, err := bot.unresolvedTickets(pending)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+			bot.sendTickets(currentChat, translator, bot.GroupId, topics)
+			return
+
+		case "/role", "/role@" + bot.Self.UserName:
+			fields := strings.Fields(args)
+			if len(fields) < 1 || len(fields) > 2 {
+				bot.sendCommandUsageRole(currentChat, translator)
+				return
+			}
+
+			user_id, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				bot.sendCommandUsageRole(currentChat, translator)
+				return
+			}
+
+			if len(fields) == 2 {
+				var role *model.Role
+				if fields[1] != "default" {
+					assigned, ok := model.ParseRole(fields[1])
+					if !ok {
+						bot.sendCommandUsageRole(currentChat, translator)
+						return
+					}
+					role = &assigned
+				}
+
+				err = bot.assignRole(user_id, role)
+				if err != nil {
+					bot.sendDatabaseError(currentChat, translator, err)
+					return
+				}
+				bot.audit(msg.From.ID, user_id, model.AuditRoleAssigned, fields[1])
+			}
+
+			bot.sendRole(currentChat, translator, user_id, bot.roleOf(user_id))
 			return
 
 		default:
@@ -950,19 +1150,13 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		return
 	}
 
-	mediaGroup, skip := bot.getMediaGroup(msg)
-	if skip {
-		return
-	}
+	mediaGroup := bot.getMediaGroup(msg)
 
 	bot.bot.RLock()
 	defer bot.bot.RUnlock()
 
-	bot.topic.Lock()
-	defer bot.topic.Unlock()
-
-	var topic model.Topic
-	err := DB().Where("topic_id", msg.MessageThreadID).Find(&topic).Error
+	topic, unlock, err := bot.findTopicByThread(msg.MessageThreadID)
+	defer unlock()
 	if err != nil {
 		bot.sendDatabaseError(currentTopic, translator, err)
 		return
@@ -972,6 +1166,20 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		return
 	}
 
+	// Observers are read only, only the commands are refused with a notice so the discussion is not flooded
+	if !bot.hasRole(msg, model.RoleAgent) {
+		if strings.HasPrefix(msg.Text, "/") {
+			bot.sendPermissionDenied(currentChat, translator)
+		}
+		return
+	}
+
+	// Internal notes stay in the topic
+	if bot.isNote(msg, mediaGroup) {
+		bot.keepNote(&topic, msg, mediaGroup)
+		return
+	}
+
 	if isUnsupportMessage {
 		bot.sendUnsupportedMessage(currentChat, translator)
 		return
@@ -984,30 +1192,82 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		},
 	}
 
+	// !name sends the snippet, it is relayed as is if no snippet is found
+	if name, ok := snippetTrigger(msg.Text); ok {
+		err = bot.sendSnippet(&topic, name, msg.From)
+		switch {
+		case err == nil:
+			return
+		case errors.Is(err, errSnippetNotFound):
+		default:
+			bot.sendError(currentChat, translator)
+			return
+		}
+	}
+
 	if strings.HasPrefix(msg.Text, "/") {
-		command, _, _ := strings.Cut(msg.Text, " ")
+		command, args, _ := strings.Cut(msg.Text, " ")
+		if !bot.canRunCommand(msg, command) {
+			bot.sendPermissionDenied(currentChat, translator)
+			return
+		}
+
 		switch command {
+		case "/s", "/s@" + bot.Self.UserName:
+			name := strings.ToLower(strings.TrimSpace(args))
+			if !snippetNameRegexp.MatchString(name) {
+				bot.sendCommandUsageSnippet(currentChat, translator)
+				return
+			}
+
+			err = bot.sendSnippet(&topic, name, msg.From)
+			switch {
+			case err == nil:
+			case errors.Is(err, errSnippetNotFound):
+				bot.sendSnippetNotFound(currentChat, translator, name)
+			default:
+				bot.sendError(currentChat, translator)
+			}
+			return
+
+		case "/snippet", "/snippet@" + bot.Self.UserName:
+			bot.handleSnippetCommand(currentChat, translator, msg, args)
+			return
+
 		case "/ban", "/ban@" + bot.Self.UserName:
-			bot.Request(botapi.DeleteForumTopicConfig{
-				BaseForum: currentForum,
-			})
-			topic.TopicId = 0
+			isBan := topic.IsBan
+			duration, reason := parseBanArgs(strings.Fields(args))
 
-			if topic.IsBan {
-				bot.sendBanUser(currentChat, translator, topic.UserId)
+			err = bot.ban(&topic, msg.From.ID, duration, reason)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
 				return
 			}
 
-			err = banTopic(&topic)
+			if !isBan {
+				bot.sendBanned(userChat, userTranslator, &topic)
+			}
+			bot.sendBanUser(currentChat, translator, &topic)
+			return
+
+		case "/resolve", "/resolve@" + bot.Self.UserName:
+			err = bot.setTicketState(&topic, model.TicketResolved, msg.From.ID)
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
 			}
 
-			DB().Model(model.Msg{}).Where("topic_id", topic.Id).Delete(nil)
+			bot.sendSuccess(currentChat, translator)
+			return
+
+		case "/pending", "/pending@" + bot.Self.UserName:
+			err = bot.setTicketState(&topic, model.TicketPending, msg.From.ID)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
 
-			bot.sendBanned(userChat, userTranslator)
-			bot.sendBanUser(currentChat, translator, topic.UserId)
+			bot.sendSuccess(currentChat, translator)
 			return
 
 		case "/unban", "/unban@" + bot.Self.UserName:
@@ -1016,11 +1276,7 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 				return
 			}
 
-			bot.Request(botapi.ReopenForumTopicConfig{
-				BaseForum: currentForum,
-			})
-
-			err = unbanTopic(&topic)
+			err = bot.unban(&topic, msg.From.ID, "")
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
@@ -1030,18 +1286,12 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			return
 
 		case "/terminate", "/terminate@" + bot.Self.UserName:
-			bot.Request(botapi.DeleteForumTopicConfig{
-				BaseForum: currentForum,
-			})
-
-			err := terminateTopic(&topic)
+			err := bot.terminate(&topic, msg.From.ID)
 			if err != nil {
 				bot.sendDatabaseError(currentChat, translator, err)
 				return
 			}
 
-			DB().Model(model.Msg{}).Where("topic_id", topic.Id).Delete(nil)
-
 			if topic.IsBan {
 				return
 			}
@@ -1049,6 +1299,27 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			bot.sendTerminated(userChat, userTranslator)
 			return
 
+		case "/export", "/export@" + bot.Self.UserName:
+			format := strings.TrimSpace(args)
+			if format == "" {
+				format = transcript.FormatHTML
+			}
+
+			t, err := transcript.Load(bot.Self.ID, topic.UserId)
+			if err != nil {
+				bot.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+			data, err := t.Render(format)
+			if err != nil {
+				bot.sendCommandUsageExport(currentChat, translator)
+				return
+			}
+
+			bot.sendFile(currentChat, t.FileName(format), data)
+			return
+
 		default:
 			if strings.HasSuffix(command, "@"+bot.Self.UserName) {
 				bot.sendUnknownCommand(currentChat, translator)
@@ -1069,8 +1340,10 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 					BaseForum: currentForum,
 				})
 				terminateTopic(&topic)
+				bot.audit(model.AuditActorSystem, topic.UserId, model.AuditBlocked, "")
 
 				bot.sendBlocked(currentGroup, translator, topic.UserId)
+				bot.publish(events.UserBlocked, &topic, nil)
 				return
 			}
 
@@ -1081,9 +1354,14 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		bot.sendError(currentChat, translator)
 	}
 
-	if topic.Verification != model.VerificationCompleted {
+	if topic.Verification != model.VerificationCompleted || topic.AwaySent {
 		topic.Verification = model.VerificationCompleted
-		saveTopic(&topic)
+		topic.AwaySent = false
+		err = saveTopic(&topic)
+		if err != nil {
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
 	}
 
 	if msg.ForwardOrigin != nil {
@@ -1103,13 +1381,14 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 				user_message_id := messageIds[i].MessageID
 
 				msgs = append(msgs, model.Msg{
+					BotId:      bot.Self.ID,
 					TopicId:    topic.Id,
 					UserMsgId:  user_message_id,
 					TopicMsgId: msg.MessageID,
 				})
 			}
 
-			DB().Create(msgs)
+			bot.saveMessages(events.MessageReplied, &topic, mediaGroup.Messages, msgs)
 			return
 		}
 
@@ -1123,11 +1402,12 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			return
 		}
 
-		DB().Create(&model.Msg{
+		bot.saveMessages(events.MessageReplied, &topic, []*botapi.Message{msg}, []model.Msg{{
+			BotId:      bot.Self.ID,
 			TopicId:    topic.Id,
 			UserMsgId:  message.MessageID,
 			TopicMsgId: msg.MessageID,
-		})
+		}})
 		return
 	}
 
@@ -1166,13 +1446,14 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 			user_message_id := messages[i].MessageID
 
 			msgs = append(msgs, model.Msg{
+				BotId:      bot.Self.ID,
 				TopicId:    topic.Id,
 				UserMsgId:  user_message_id,
 				TopicMsgId: msg.MessageID,
 			})
 		}
 
-		DB().Create(msgs)
+		bot.saveMessages(events.MessageReplied, &topic, mediaGroup.Messages, msgs)
 		return
 	}
 
@@ -1186,11 +1467,12 @@ func (bot *Bot) handleTopicNewMessage(msg *botapi.Message) {
 		return
 	}
 
-	DB().Create(&model.Msg{
+	bot.saveMessages(events.MessageReplied, &topic, []*botapi.Message{msg}, []model.Msg{{
+		BotId:      bot.Self.ID,
 		TopicId:    topic.Id,
 		UserMsgId:  message.MessageID,
 		TopicMsgId: msg.MessageID,
-	})
+	}})
 }
 
 func (bot *Bot) handleTopicEditMessage(msg *botapi.Message) {
@@ -1246,11 +1528,8 @@ func (bot *Bot) handleTopicEditMessage(msg *botapi.Message) {
 	bot.bot.RLock()
 	defer bot.bot.RUnlock()
 
-	bot.topic.Lock()
-	defer bot.topic.Unlock()
-
-	var topic model.Topic
-	err := DB().Where("topic_id", msg.MessageThreadID).Find(&topic).Error
+	topic, unlock, err := bot.findTopicByThread(msg.MessageThreadID)
+	defer unlock()
 	if err != nil {
 		bot.sendDatabaseError(currentChat, translator, err)
 		return
@@ -1260,6 +1539,11 @@ func (bot *Bot) handleTopicEditMessage(msg *botapi.Message) {
 		return
 	}
 
+	// Observers are read only, notes are never sent
+	if !bot.hasRole(msg, model.RoleAgent) || bot.isNote(msg, nil) {
+		return
+	}
+
 	if isUnsupportMessage {
 		bot.sendUnsupportedMessage(currentChat, translator)
 		return
@@ -1302,8 +1586,10 @@ func (bot *Bot) handleTopicEditMessage(msg *botapi.Message) {
 					BaseForum: currentForum,
 				})
 				terminateTopic(&topic)
+				bot.audit(model.AuditActorSystem, topic.UserId, model.AuditBlocked, "")
 
 				bot.sendBlocked(currentGroup, translator, topic.UserId)
+				bot.publish(events.UserBlocked, &topic, nil)
 				return
 			}
 
@@ -1314,4 +1600,6 @@ func (bot *Bot) handleTopicEditMessage(msg *botapi.Message) {
 		bot.sendError(currentChat, translator)
 		return
 	}
+
+	bot.archiveEdit(model.DirectionOutgoing, &topic, msg, message)
 }
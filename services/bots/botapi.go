@@ -10,9 +10,10 @@ import (
 
 type BotAPI struct {
 	*botapi.BotAPI
-	bot         sync.RWMutex
-	topic       sync.Mutex
-	mediaGroups mediaGroupCache
+	bot                sync.RWMutex
+	topics             topicLocker
+	mediaGroups        mediaGroupCache
+	pendingMediaGroups sync.Map // *botapi.Message -> *MediaGroup
 }
 
 func NewBotAPI(b *botapi.BotAPI) *BotAPI {
@@ -22,7 +23,9 @@ func NewBotAPI(b *botapi.BotAPI) *BotAPI {
 		close(mediaGroup.done)
 	})
 
-	return &BotAPI{BotAPI: b, mediaGroups: mediaGroups}
+	api := &BotAPI{BotAPI: b, mediaGroups: mediaGroups}
+	api.topics.bot_id = b.Self.ID
+	return api
 }
 
 func (bot *BotAPI) Request(c botapi.Chattable) (*botapi.APIResponse, error) {
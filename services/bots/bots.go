@@ -1,12 +1,16 @@
 package bots
 
 import (
+	. "Topicgram/database"
 	"Topicgram/i18n"
 	"Topicgram/model"
 	"Topicgram/utils"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
 
 	botapi "github.com/OvyFlash/telegram-bot-api"
 	"github.com/gin-gonic/gin"
@@ -14,28 +18,37 @@ import (
 )
 
 var (
-	bot         *Bot
-	secretToken string
+	bots   = make(map[int64]*Bot)
+	botsMu sync.RWMutex
 )
 
 func Load(botConfig *model.BotConfig) error {
-	secretToken = utils.MD5(botConfig.WebHook.Host) + utils.SHA256(botConfig.Token)
+	bot, err := newBot(botConfig)
+	if err != nil {
+		return err
+	}
+
+	return register(bot)
+}
+
+func newBot(botConfig *model.BotConfig) (*Bot, error) {
+	b, err := botapi.NewBotAPIWithClient(botConfig.Token, botapi.APIEndpoint, utils.BotClient)
+	if err != nil {
+		return nil, err
+	}
+
+	secretToken := utils.MD5(botConfig.WebHook.Host) + utils.SHA256(botConfig.Token)
 
 	webhookConfig := botapi.WebhookConfig{
 		URL: &url.URL{
 			Scheme: "https",
 			Host:   botConfig.WebHook.Host,
-			Path:   "/topicgram/webhook",
+			Path:   fmt.Sprintf("/topicgram/webhook/%d", b.Self.ID),
 		},
 		MaxConnections: 100,
 		SecretToken:    secretToken,
 	}
 
-	b, err := botapi.NewBotAPIWithClient(botConfig.Token, botapi.APIEndpoint, utils.BotClient)
-	if err != nil {
-		return err
-	}
-
 	{
 		chatConfig := botapi.ChatConfig{
 			ChatID: botConfig.GroupId,
@@ -45,11 +58,11 @@ func Load(botConfig *model.BotConfig) error {
 			ChatConfig: chatConfig,
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if !chat.IsForum {
-			return fmt.Errorf("[Group %d] Topic mode required", botConfig.GroupId)
+			return nil, fmt.Errorf("[Group %d] Topic mode required", botConfig.GroupId)
 		}
 
 		member, err := b.GetChatMember(botapi.GetChatMemberConfig{
@@ -59,21 +72,26 @@ func Load(botConfig *model.BotConfig) error {
 			},
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if member.Status != "administrator" {
-			return fmt.Errorf("[Group %d] Group administrator required", botConfig.GroupId)
+			return nil, fmt.Errorf("[Group %d] Group administrator required", botConfig.GroupId)
 		}
 
 		if !member.CanDeleteMessages || !member.CanPinMessages || !member.CanManageTopics {
-			return fmt.Errorf("[Group %d] Permissions (delete_messages, pin_messages, manage_topics) required", botConfig.GroupId)
+			return nil, fmt.Errorf("[Group %d] Permissions (delete_messages, pin_messages, manage_topics) required", botConfig.GroupId)
 		}
 	}
 
-	_, err = b.Request(webhookConfig)
+	switch botConfig.Mode {
+	case model.BotModePolling:
+		_, err = b.Request(botapi.DeleteWebhookConfig{})
+	default:
+		_, err = b.Request(webhookConfig)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	i18n.Range(func(code string, translator i18n.Translator) {
@@ -86,6 +104,17 @@ func Load(botConfig *model.BotConfig) error {
 				{Command: "ban", Description: translator.CommandDescription_Ban()},
 				{Command: "unban", Description: translator.CommandDescription_Unban()},
 				{Command: "terminate", Description: translator.CommandDescription_Terminate()},
+				{Command: "search", Description: translator.CommandDescription_Search()},
+				{Command: "export", Description: translator.CommandDescription_Export()},
+				{Command: "note", Description: translator.CommandDescription_Note()},
+				{Command: "s", Description: translator.CommandDescription_S()},
+				{Command: "snippet", Description: translator.CommandDescription_Snippet()},
+				{Command: "role", Description: translator.CommandDescription_Role()},
+				{Command: "audit", Description: translator.CommandDescription_Audit()},
+				{Command: "away", Description: translator.CommandDescription_Away()},
+				{Command: "resolve", Description: translator.CommandDescription_Resolve()},
+				{Command: "pending", Description: translator.CommandDescription_Pending()},
+				{Command: "tickets", Description: translator.CommandDescription_Tickets()},
 			},
 			Scope: &botapi.BotCommandScope{
 				Type:   "chat",
@@ -95,20 +124,164 @@ func Load(botConfig *model.BotConfig) error {
 		})
 	})
 
-	mediaGroups := NewMediaGroupCache()
-	mediaGroups.AddAboutToDeleteItemCallback(func(item mediaGroupItem) {
-		mediaGroup := item.Data()
-		close(mediaGroup.done)
-	})
+	return &Bot{BotConfig: botConfig, BotAPI: NewBotAPI(b), secretToken: secretToken}, nil
+}
 
-	bot = &Bot{BotConfig: botConfig, BotAPI: &BotAPI{BotAPI: b, mediaGroups: mediaGroups}}
-	clog.Success("[Bot] Load completed")
+func register(bot *Bot) error {
+	botsMu.Lock()
+	defer botsMu.Unlock()
+
+	if _, ok := bots[bot.Self.ID]; ok {
+		return fmt.Errorf("[Bot %d] Already loaded", bot.Self.ID)
+	}
+
+	bot.startPolling()
+	bots[bot.Self.ID] = bot
+	clog.Successf("[Bot %d] Load completed", bot.Self.ID)
 	return nil
 }
 
+// swap replaces the running instance of the bot with the new one, e.g. after the token is changed.
+// The webhook is kept, it is already set by the new instance.
+func swap(bot *Bot) {
+	botsMu.Lock()
+	defer botsMu.Unlock()
+
+	if old, ok := bots[bot.Self.ID]; ok && old.stopPolling != nil {
+		old.stopPolling()
+	}
+
+	bot.startPolling()
+	bots[bot.Self.ID] = bot
+	clog.Successf("[Bot %d] Reload completed", bot.Self.ID)
+}
+
+func (bot *Bot) startPolling() {
+	if bot.Mode != model.BotModePolling {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bot.stopPolling = cancel
+	go bot.poll(ctx, bot.dispatch)
+}
+
+// Unload stops serving the bot, webhook or polling updates will no longer be received.
+func Unload(bot_id int64) bool {
+	bot, ok := unregister(bot_id)
+	if !ok {
+		return false
+	}
+
+	if bot.stopPolling == nil {
+		bot.Request(botapi.DeleteWebhookConfig{})
+	}
+
+	return true
+}
+
+// unregister stops serving the bot on this node only, the webhook is kept for other nodes.
+func unregister(bot_id int64) (*Bot, bool) {
+	botsMu.Lock()
+	bot, ok := bots[bot_id]
+	delete(bots, bot_id)
+	botsMu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if bot.stopPolling != nil {
+		bot.stopPolling()
+	}
+
+	clog.Infof("[Bot %d] Unloaded", bot_id)
+	return bot, true
+}
+
+// AdoptLegacyRows assigns topics and messages created before multi-bot support to the bot, 0 for the only loaded bot.
+func AdoptLegacyRows(bot_id int64) error {
+	if bot_id == 0 {
+		botsMu.RLock()
+		if len(bots) == 1 {
+			for id := range bots {
+				bot_id = id
+			}
+		}
+		botsMu.RUnlock()
+	}
+
+	if bot_id == 0 {
+		var count int64
+		err := DB().Model(model.Topic{}).Where("bot_id", 0).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			clog.Errorf("[Database] %d topics created before multi-bot support are not adopted, please set LegacyBotId", count)
+		}
+		return nil
+	}
+
+	err := DB().Model(model.Topic{}).Where("bot_id", 0).Update("bot_id", bot_id).Error
+	if err != nil {
+		return err
+	}
+
+	return DB().Model(model.Msg{}).Where("bot_id", 0).Update("bot_id", bot_id).Error
+}
+
+func Get(bot_id int64) (*Bot, bool) {
+	botsMu.RLock()
+	defer botsMu.RUnlock()
+
+	bot, ok := bots[bot_id]
+	return bot, ok
+}
+
+// All returns the bots loaded on this node.
+func All() []*Bot {
+	botsMu.RLock()
+	defer botsMu.RUnlock()
+
+	list := make([]*Bot, 0, len(bots))
+	for _, bot := range bots {
+		list = append(list, bot)
+	}
+	return list
+}
+
+func Stop() {
+	if manager != nil && manager.stopPolling != nil {
+		manager.stopPolling()
+	}
+
+	botsMu.RLock()
+	defer botsMu.RUnlock()
+
+	for _, bot := range bots {
+		if bot.stopPolling != nil {
+			bot.stopPolling()
+		}
+	}
+}
+
 func HookHandler(c *gin.Context) {
+	bot_id, err := strconv.ParseInt(c.Param("botId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return
+	}
+
+	bot, ok := Get(bot_id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return
+	}
+
 	token := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
-	if token != secretToken {
+	if token != bot.secretToken {
 		c.JSON(http.StatusForbidden, gin.H{"error": "bot not found"})
 		return
 	}
@@ -119,6 +292,6 @@ func HookHandler(c *gin.Context) {
 		return
 	}
 
-	go bot.handleUpdate(update)
+	bot.dispatch(update)
 	c.String(200, "OK")
 }
@@ -4,6 +4,9 @@ import (
 	"Topicgram/i18n"
 	"Topicgram/model"
 	"Topicgram/services/captcha"
+	"Topicgram/services/events"
+	"slices"
+	"strconv"
 	"time"
 
 	botapi "github.com/OvyFlash/telegram-bot-api"
@@ -14,40 +17,322 @@ import (
 
 const (
 	CAPTCHA_DURATION = time.Minute
+	CAPTCHA_ATTEMPTS = 3
+
+	CAPTCHA_LOCKOUT_AFTER = 3
+	CAPTCHA_LOCKOUT       = time.Minute
+	CAPTCHA_LOCKOUT_MAX   = 24 * time.Hour
 
 	CAPTCHA_OLDER_USER_ID = 1000000000
 )
 
+// defaultCaptchaRules bypasses premium users, older users and users with NFT usernames.
+var defaultCaptchaRules = []model.CaptchaRule{
+	{Type: model.CaptchaRulePremium},
+	{Type: model.CaptchaRuleUserId, UserId: CAPTCHA_OLDER_USER_ID},
+	{Type: model.CaptchaRuleUsername, Usernames: 2},
+}
+
 func (bot *Bot) shouldSendCaptcha(msg *botapi.Message) bool {
-	// Premium bypass
-	if msg.From.IsPremium {
-		clog.Debugf("[Bot %d] user %d is premium, bypass the captcha", bot.Self.ID, msg.From.ID)
+	challenge := bot.evaluateCaptchaRules(msg)
+	if challenge && bot.Captcha.DryRun {
+		clog.Infof("[Bot %d] dry run, user %d would be challenged", bot.Self.ID, msg.From.ID)
 		return false
 	}
 
-	// Older user bypass
-	if msg.From.ID <= CAPTCHA_OLDER_USER_ID {
-		clog.Debugf("[Bot %d] user %d is older user, bypass the captcha", bot.Self.ID, msg.From.ID)
-		return false
+	return challenge
+}
+
+// evaluateCaptchaRules evaluates the rules in order, the first matched rule decides.
+func (bot *Bot) evaluateCaptchaRules(msg *botapi.Message) bool {
+	rules := bot.Captcha.Rules
+	if len(rules) == 0 {
+		rules = defaultCaptchaRules
 	}
 
-	chat, _ := bot.GetChat(botapi.ChatInfoConfig{
-		ChatConfig: msg.Chat.ChatConfig(),
-	})
+	// The chat is only requested if a rule requires it
+	var chat *botapi.ChatFullInfo
+	getChat := func() *botapi.ChatFullInfo {
+		if chat == nil {
+			info, _ := bot.GetChat(botapi.ChatInfoConfig{
+				ChatConfig: msg.Chat.ChatConfig(),
+			})
+			chat = &info
+		}
+		return chat
+	}
 
-	// NFT user bypass
-	if len(chat.ActiveUsernames) > 1 {
-		clog.Debugf("[Bot %d] user %d assigns NFT username %v, bypass the captcha", bot.Self.ID, msg.From.ID, chat.ActiveUsernames)
-		return false
+	for i, rule := range rules {
+		var matched bool
+		switch rule.Type {
+		case model.CaptchaRuleAlways:
+			clog.Debugf("[Bot %d] captcha rule #%d (%s) matched user %d, challenge", bot.Self.ID, i, rule.Type, msg.From.ID)
+			return true
+		case model.CaptchaRuleNever:
+			matched = true
+		case model.CaptchaRulePremium:
+			matched = msg.From.IsPremium
+		case model.CaptchaRuleUserId:
+			matched = msg.From.ID <= rule.UserId
+		case model.CaptchaRuleUsername:
+			matched = len(getChat().ActiveUsernames) >= max(rule.Usernames, 1)
+		case model.CaptchaRuleLanguage:
+			matched = slices.Contains(rule.LanguageCodes, msg.From.LanguageCode)
+		case model.CaptchaRulePhoto:
+			matched = getChat().Photo != nil
+		case model.CaptchaRuleAllowlist:
+			matched = slices.Contains(rule.UserIds, msg.From.ID)
+		}
+
+		if matched {
+			clog.Debugf("[Bot %d] captcha rule #%d (%s) matched user %d, bypass the captcha", bot.Self.ID, i, rule.Type, msg.From.ID)
+			return false
+		}
+
+		clog.Debugf("[Bot %d] captcha rule #%d (%s) not matched user %d", bot.Self.ID, i, rule.Type, msg.From.ID)
 	}
 
 	return true
 }
 
-func (bot *Bot) captchaMath(translator i18n.Translator, topic *model.Topic) (*formatter.Builder, botapi.InlineKeyboardMarkup) {
+func (bot *Bot) captchaDuration() time.Duration {
+	if bot.Captcha.Duration <= 0 {
+		return CAPTCHA_DURATION
+	}
+	return time.Duration(bot.Captcha.Duration) * time.Second
+}
+
+// challenge returns the configured captcha, math captcha is the default.
+func (bot *Bot) challenge() captcha.Challenge {
+	challenge, ok := captcha.Get(bot.Captcha.Type)
+	if !ok {
+		challenge, _ = captcha.Get(captcha.TypeMath)
+	}
+	return challenge
+}
+
+func (bot *Bot) isTypedCaptcha() bool {
+	return bot.Captcha.Answer == model.CaptchaAnswerText
+}
+
+func (bot *Bot) captchaAttempts() int {
+	if bot.Captcha.Attempts <= 0 {
+		return CAPTCHA_ATTEMPTS
+	}
+	return bot.Captcha.Attempts
+}
+
+// newCaptcha issues a new challange of the topic.
+func (bot *Bot) newCaptcha(translator i18n.Translator, topic *model.Topic) (captcha.Challenge, captcha.Puzzle, *formatter.Builder) {
 	topic.ChallangeId = rand.Crypto.Uint64()
+	topic.ChallangeTry = 0
+
+	challenge := bot.challenge()
+	puzzle := challenge.Generate(bot.Token, topic.ChallangeId)
+
+	var description *formatter.Builder
+	if puzzle.Type == captcha.TypeWebApp {
+		puzzle.ReplyMarkup = captcha.WebAppButton(translator.CaptchaWebAppButton(), bot.webAppURL())
+		description = translator.CaptchaWebApp(bot.captchaDuration())
+		return challenge, puzzle, description
+	}
+
+	if bot.isTypedCaptcha() {
+		// The answer is typed as a message
+		puzzle.ReplyMarkup = botapi.InlineKeyboardMarkup{}
+
+		switch puzzle.Type {
+		case captcha.TypeImage:
+			description = translator.CaptchaImageTyped(bot.captchaDuration(), bot.captchaAttempts())
+		default:
+			description = translator.CaptchaMathTyped(bot.captchaDuration(), puzzle.Problem, bot.captchaAttempts())
+		}
+		return challenge, puzzle, description
+	}
+
+	switch puzzle.Type {
+	case captcha.TypeImage:
+		description = translator.CaptchaImage(bot.captchaDuration())
+	default:
+		description = translator.CaptchaMath(bot.captchaDuration(), puzzle.Problem)
+	}
+
+	return challenge, puzzle, description
+}
+
+// issueCaptcha sends a new challange to the user, the caller must hold the lock of the user.
+func (bot *Bot) issueCaptcha(currentChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic, msg *botapi.Message) {
+	challenge, puzzle, description := bot.newCaptcha(translator, topic)
+	err := bot.sendCaptcha(currentChat, translator, challenge, puzzle, description)
+	if err != nil {
+		return
+	}
+
+	topic.BotId = bot.Self.ID
+	topic.UserId = msg.From.ID
+	topic.Verification = model.VerificationNotCompleted
+	topic.ChallangeSent = time.Now().Unix()
+	topic.LanguageCode = msg.From.LanguageCode
+
+	err = saveTopic(topic)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	if topic.TopicId != 0 {
+		bot.sendCaptchaNotify(botapi.BaseChat{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+			MessageThreadID: topic.TopicId,
+		}, i18n.GetOrDefault(bot.LanguageCode))
+	}
+}
+
+// captchaCooldown returns the cooldown before a new challange, it grows exponentially after the lockout threshold.
+func (bot *Bot) captchaCooldown(failures int) time.Duration {
+	lockoutAfter := bot.Captcha.LockoutAfter
+	if lockoutAfter <= 0 {
+		lockoutAfter = CAPTCHA_LOCKOUT_AFTER
+	}
+
+	if failures < lockoutAfter {
+		return 0
+	}
+
+	cooldown := CAPTCHA_LOCKOUT
+	if bot.Captcha.Lockout > 0 {
+		cooldown = time.Duration(bot.Captcha.Lockout) * time.Second
+	}
+
+	for range failures - lockoutAfter {
+		cooldown *= 2
+		if cooldown >= CAPTCHA_LOCKOUT_MAX {
+			return CAPTCHA_LOCKOUT_MAX
+		}
+	}
+
+	return cooldown
+}
+
+// handleCaptchaAnswer treats the message as a typed answer of the challange, the caller must hold the lock of the user.
+func (bot *Bot) handleCaptchaAnswer(currentChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic, msg *botapi.Message) {
+	if msg.Text == "" {
+		bot.sendCaptchaNotCompleted(currentChat, translator)
+		return
+	}
+
+	challangeId := topic.ChallangeId
+	notAfter := time.Unix(topic.ChallangeSent, 0).Add(bot.captchaDuration())
+
+	if time.Now().After(notAfter) {
+		topic.ChallangeSent = 0
+		topic.ChallangeId = 0
+		topic.ChallangeTry = 0
+		err := saveTopic(topic)
+		if err != nil {
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		bot.failCaptcha(currentChat, translator, topic)
+		return
+	}
+
+	if bot.challenge().Check(bot.Token, challangeId, msg.Text) {
+		bot.completeCaptcha(currentChat, translator, topic)
+		return
+	}
+
+	topic.ChallangeTry++
+	remaining := bot.captchaAttempts() - topic.ChallangeTry
+	if remaining <= 0 {
+		topic.ChallangeSent = 0
+		topic.ChallangeId = 0
+		topic.ChallangeTry = 0
+	}
+
+	err := saveTopic(topic)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	if remaining <= 0 {
+		bot.failCaptcha(currentChat, translator, topic)
+		return
+	}
+
+	bot.sendCaptchaWrongAnswer(currentChat, translator, remaining)
+}
+
+// completeCaptcha marks the user verified, the caller must hold the lock of the user.
+func (bot *Bot) completeCaptcha(currentChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic) {
+	topic.Verification = model.VerificationCompleted
+	topic.ChallangeSent = 0
+	topic.ChallangeId = 0
+	topic.ChallangeTry = 0
+	topic.CaptchaFailures = 0
+	topic.CaptchaRetryAt = 0
+	err := saveTopic(topic)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	bot.sendCaptchaCompleted(currentChat, translator)
+	bot.audit(topic.UserId, topic.UserId, model.AuditCaptchaPassed, "")
+	bot.publish(events.CaptchaPassed, topic, nil)
+
+	if topic.TopicId == 0 {
+		return
+	}
+
+	botTranslator := i18n.GetOrDefault(bot.LanguageCode)
+	botTopic := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: bot.GroupId,
+		},
+		MessageThreadID: topic.TopicId,
+	}
+	bot.sendCaptchaCompletedNotify(botTopic, botTranslator)
+}
+
+// failCaptcha counts the failure and notifies the user, the user is banned after too many failures.
+// The challange must be cleared by the caller, the caller must hold the lock of the user.
+func (bot *Bot) failCaptcha(currentChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic) {
+	topic.CaptchaFailures++
+	topic.CaptchaRetryAt = 0
+	if cooldown := bot.captchaCooldown(topic.CaptchaFailures); cooldown > 0 {
+		topic.CaptchaRetryAt = time.Now().Add(cooldown).Unix()
+	}
+
+	bot.audit(topic.UserId, topic.UserId, model.AuditCaptchaFailed, strconv.Itoa(topic.CaptchaFailures))
+
+	if bot.Captcha.BanAfter > 0 && topic.CaptchaFailures >= bot.Captcha.BanAfter {
+		err := bot.ban(topic, model.AuditActorSystem, 0, "")
+		if err != nil {
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		bot.sendBanned(currentChat, translator, topic)
+		bot.sendCaptchaAutoBanned(botapi.BaseChat{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+		}, i18n.GetOrDefault(bot.LanguageCode), topic.UserId, topic.CaptchaFailures)
+		bot.publish(events.CaptchaFailed, topic, nil)
+		return
+	}
+
+	err := saveTopic(topic)
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
 
-	problem, replyMarkup := captcha.NewMath(bot.Token, topic.ChallangeId)
-	description := translator.CaptchaMath(CAPTCHA_DURATION, problem)
-	return description, replyMarkup
+	bot.sendCaptchaFailed(currentChat, translator)
+	bot.publish(events.CaptchaFailed, topic, nil)
 }
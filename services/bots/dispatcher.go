@@ -0,0 +1,164 @@
+package bots
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const (
+	defaultDispatcherWorkers   = 16
+	defaultDispatcherQueueSize = 100
+)
+
+var dispatcher *Dispatcher
+
+type dispatchJob struct {
+	bot    *Bot
+	update *botapi.Update
+}
+
+// Dispatcher processes updates of the same chat in order, and updates of different chats in parallel.
+type Dispatcher struct {
+	seed   maphash.Seed
+	queues []chan dispatchJob
+
+	enqueued    atomic.Int64
+	processed   atomic.Int64
+	blocked     atomic.Int64
+	blockedTime atomic.Int64
+}
+
+type DispatcherStats struct {
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queue_size"`
+
+	Depth    int   `json:"depth"`
+	MaxDepth int   `json:"max_depth"` // Depth of the busiest worker
+	Enqueued int64 `json:"enqueued"`
+
+	Processed int64 `json:"processed"`
+
+	Blocked     int64         `json:"blocked"` // Updates waited for a full queue
+	BlockedTime time.Duration `json:"blocked_time"`
+}
+
+func StartDispatcher(workers, queueSize int) {
+	if workers <= 0 {
+		workers = defaultDispatcherWorkers
+	}
+
+	if queueSize <= 0 {
+		queueSize = defaultDispatcherQueueSize
+	}
+
+	d := &Dispatcher{
+		seed:   maphash.MakeSeed(),
+		queues: make([]chan dispatchJob, workers),
+	}
+
+	for i := range d.queues {
+		d.queues[i] = make(chan dispatchJob, queueSize)
+		go d.work(d.queues[i])
+	}
+
+	dispatcher = d
+	clog.Infof("[Dispatcher] Started %d workers, queue size: %d", workers, queueSize)
+}
+
+func (d *Dispatcher) work(queue chan dispatchJob) {
+	for job := range queue {
+		job.bot.handleUpdate(job.update)
+		d.processed.Add(1)
+	}
+}
+
+func (d *Dispatcher) queue(bot_id int64, update *botapi.Update) chan dispatchJob {
+	var chat_id int64
+	if chat := update.FromChat(); chat != nil {
+		chat_id = chat.ID
+	}
+
+	hash := maphash.Comparable(d.seed, [2]int64{bot_id, chat_id})
+	return d.queues[hash%uint64(len(d.queues))]
+}
+
+// Dispatch blocks while the queue of the chat is full.
+func (d *Dispatcher) Dispatch(bot *Bot, update *botapi.Update) {
+	job := dispatchJob{bot: bot, update: update}
+	queue := d.queue(bot.Self.ID, update)
+
+	d.enqueued.Add(1)
+
+	select {
+	case queue <- job:
+		return
+	default:
+	}
+
+	d.blocked.Add(1)
+	clog.Debugf("[Dispatcher] queue full, bot %d waiting", bot.Self.ID)
+
+	start := time.Now()
+	queue <- job
+	d.blockedTime.Add(int64(time.Since(start)))
+}
+
+func (d *Dispatcher) Stats() DispatcherStats {
+	stats := DispatcherStats{
+		Workers:     len(d.queues),
+		QueueSize:   cap(d.queues[0]),
+		Enqueued:    d.enqueued.Load(),
+		Processed:   d.processed.Load(),
+		Blocked:     d.blocked.Load(),
+		BlockedTime: time.Duration(d.blockedTime.Load()),
+	}
+
+	for _, queue := range d.queues {
+		depth := len(queue)
+		stats.Depth += depth
+		stats.MaxDepth = max(stats.MaxDepth, depth)
+	}
+
+	return stats
+}
+
+func GetDispatcherStats() (DispatcherStats, bool) {
+	if dispatcher == nil {
+		return DispatcherStats{}, false
+	}
+
+	return dispatcher.Stats(), true
+}
+
+func (bot *Bot) dispatch(update *botapi.Update) {
+	if update.Message != nil {
+		ready, ok := bot.collectMediaGroup(update.Message)
+		if !ok {
+			return
+		}
+
+		// The album is enqueued as one job once all its messages are collected
+		if ready != nil {
+			go func() {
+				<-ready
+				bot.enqueue(update)
+			}()
+			return
+		}
+	}
+
+	bot.enqueue(update)
+}
+
+func (bot *Bot) enqueue(update *botapi.Update) {
+	if dispatcher == nil {
+		bot.handleUpdate(update)
+		return
+	}
+
+	dispatcher.Dispatch(bot, update)
+}
@@ -0,0 +1,45 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/events"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+func (bot *Bot) publish(event string, topic *model.Topic, data map[string]any) {
+	if data == nil {
+		data = make(map[string]any)
+	}
+
+	data["user_id"] = topic.UserId
+	if _, ok := data["topic_id"]; !ok {
+		data["topic_id"] = topic.TopicId
+	}
+
+	events.Publish(bot.Self.ID, event, data)
+}
+
+// saveMessages records the relayed messages and publishes them as event.
+func (bot *Bot) saveMessages(event string, topic *model.Topic, messages []*botapi.Message, msgs []model.Msg) {
+	DB().Create(msgs)
+
+	direction := model.DirectionIncoming
+	if event == events.MessageReplied {
+		direction = model.DirectionOutgoing
+	}
+	bot.archive(direction, topic, messages, msgs)
+
+	for i, msg := range messages {
+		if i >= len(msgs) {
+			break
+		}
+
+		bot.publish(event, topic, map[string]any{
+			"user_msg_id":  msgs[i].UserMsgId,
+			"topic_msg_id": msgs[i].TopicMsgId,
+			"message":      msg,
+		})
+	}
+}
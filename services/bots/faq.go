@@ -0,0 +1,94 @@
+package bots
+
+import (
+	"Topicgram/i18n"
+	"strings"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const (
+	faqCallbackPrefix  = "faq:"
+	faqCallbackHelpful = "yes"
+	faqCallbackHuman   = "human"
+)
+
+func faqMarkup(translator i18n.Translator) botapi.InlineKeyboardMarkup {
+	return botapi.NewInlineKeyboardMarkup(botapi.NewInlineKeyboardRow(
+		botapi.NewInlineKeyboardButtonData(translator.FAQHelpful(), faqCallbackPrefix+faqCallbackHelpful),
+		botapi.NewInlineKeyboardButtonData(translator.FAQHuman(), faqCallbackPrefix+faqCallbackHuman),
+	))
+}
+
+// faqAnswer returns the answer of the first matching rule, ok is false if no rule matched.
+// Commands, forwarded messages and albums are never answered.
+func (bot *Bot) faqAnswer(msg *botapi.Message) (string, bool) {
+	if len(bot.FAQ) == 0 || msg.MediaGroupID != "" || msg.ForwardOrigin != nil {
+		return "", false
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	if text == "" || strings.HasPrefix(text, "/") {
+		return "", false
+	}
+
+	for i := range bot.FAQ {
+		rule := &bot.FAQ[i]
+		if !rule.Match(text) {
+			continue
+		}
+
+		answer, ok := rule.Answer(msg.From.LanguageCode)
+		if ok {
+			return answer, true
+		}
+	}
+
+	return "", false
+}
+
+// handleFAQCallback handles the Did this help? buttons, the message answered is forwarded to the topic if the user asks for a human.
+func (bot *Bot) handleFAQCallback(callback *botapi.CallbackQuery, data string) {
+	translator := i18n.GetOrDefault(callback.From.LanguageCode)
+
+	msg := callback.Message
+	if msg == nil || (data != faqCallbackHelpful && data != faqCallbackHuman) {
+		bot.Request(botapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	// Removing the buttons also guards against the second click
+	_, err := bot.Request(botapi.EditMessageReplyMarkupConfig{
+		BaseEdit: botapi.BaseEdit{
+			BaseChatMessage: botapi.BaseChatMessage{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: msg.Chat.ID,
+				},
+				MessageID: msg.MessageID,
+			},
+		},
+	})
+	if err != nil {
+		bot.Request(botapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	if data == faqCallbackHelpful {
+		bot.Request(botapi.NewCallback(callback.ID, translator.FAQThanks()))
+		return
+	}
+
+	bot.Request(botapi.NewCallback(callback.ID, translator.FAQHandoff()))
+
+	// The question may be deleted by the user
+	question := msg.ReplyToMessage
+	if question == nil || question.From == nil || question.From.ID != callback.From.ID {
+		return
+	}
+
+	bot.handleUserMessage(question, true)
+}
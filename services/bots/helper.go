@@ -3,9 +3,14 @@ package bots
 import (
 	. "Topicgram/database"
 	"Topicgram/model"
+	"Topicgram/utils"
+	"errors"
+	"strconv"
 	"strings"
+	"time"
 
 	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gorm.io/gorm"
 )
 
 func isServiceMessage(msg *botapi.Message) bool {
@@ -49,12 +54,53 @@ func isThreadNotFound(err *botapi.Error) bool {
 	return strings.Contains(err.Message, "message thread not found")
 }
 
+func isNotModified(err *botapi.Error) bool {
+	return strings.Contains(err.Message, "message is not modified")
+}
+
+func createTopic(topic *model.Topic) error {
+	err := DB().Create(topic).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrTopicConflict
+	}
+
+	return err
+}
+
+// saveTopic updates the topic only if it is not changed by others since loaded.
 func saveTopic(topic *model.Topic) error {
 	if topic.Id == 0 {
-		return DB().Create(topic).Error
+		return createTopic(topic)
+	}
+
+	version := topic.Version
+	topic.Version++
+
+	result := DB().Model(topic).Where("version", version).Select("*").Updates(topic)
+	if result.Error != nil {
+		topic.Version = version
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		topic.Version = version
+		return ErrTopicConflict
+	}
+
+	return nil
+}
+
+func deleteTopic(topic *model.Topic) error {
+	result := DB().Where("version", topic.Version).Delete(topic)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTopicConflict
 	}
 
-	return DB().Save(topic).Error
+	return nil
 }
 
 func banTopic(topic *model.Topic) error {
@@ -63,33 +109,62 @@ func banTopic(topic *model.Topic) error {
 	topic.ChallangeId = 0
 	topic.ChallangeSent = 0
 
-	if topic.Id == 0 {
-		return DB().Create(topic).Error
-	}
-
-	return DB().Save(topic).Error
+	return saveTopic(topic)
 }
 
 func unbanTopic(topic *model.Topic) error {
 	topic.IsBan = false
+	topic.BanUntil = 0
+	topic.BanReason = ""
 
 	if topic.TopicId == 0 {
-		return DB().Delete(topic).Error
+		return deleteTopic(topic)
 	}
 
 	topic.Verification = model.VerificationCompleted
 	topic.ChallangeId = 0
 	topic.ChallangeSent = 0
-	return DB().Save(topic).Error
+	topic.CaptchaFailures = 0
+	topic.CaptchaRetryAt = 0
+	return saveTopic(topic)
 }
 
 func terminateTopic(topic *model.Topic) error {
 	DB().Model(model.Msg{}).Where("topic_id", topic.Id).Delete(nil)
 	topic.TopicId = 0
+	topic.SenderMsgId = 0
 
 	if topic.IsBan || topic.Verification == model.VerificationNotCompleted {
-		return DB().Save(topic).Error
+		return saveTopic(topic)
+	}
+
+	return deleteTopic(topic)
+}
+
+// parseBanArgs parses [duration] [reason] of the ban command.
+func parseBanArgs(fields []string) (time.Duration, string) {
+	if len(fields) == 0 {
+		return 0, ""
+	}
+
+	duration, ok := utils.ParseDuration(fields[0])
+	if ok {
+		fields = fields[1:]
+	}
+
+	return duration, strings.Join(fields, " ")
+}
+
+func BotIdFromToken(token string) (int64, bool) {
+	id, secret, ok := strings.Cut(token, ":")
+	if !ok || secret == "" {
+		return 0, false
+	}
+
+	bot_id, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || bot_id <= 0 {
+		return 0, false
 	}
 
-	return DB().Delete(topic).Error
+	return bot_id, true
 }
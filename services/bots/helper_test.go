@@ -0,0 +1,30 @@
+package bots
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBanArgs(t *testing.T) {
+	tests := []struct {
+		args       string
+		wantDur    time.Duration
+		wantReason string
+	}{
+		{"", 0, ""},
+		{"7d", 7 * 24 * time.Hour, ""},
+		{"2w spam links", 14 * 24 * time.Hour, "spam links"},
+		{"30m flood", 30 * time.Minute, "flood"},
+		{"spam links", 0, "spam links"},
+		{"0d spam", 0, "0d spam"},
+		{"spam 7d", 0, "spam 7d"},
+	}
+
+	for _, test := range tests {
+		duration, reason := parseBanArgs(strings.Fields(test.args))
+		if duration != test.wantDur || reason != test.wantReason {
+			t.Errorf("parseBanArgs(%q) = %v, %q, want %v, %q", test.args, duration, reason, test.wantDur, test.wantReason)
+		}
+	}
+}
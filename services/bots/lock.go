@@ -0,0 +1,84 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/cluster"
+	"fmt"
+	"sync"
+
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const topicLockStripes = 256
+
+// topicLocker serializes the handlers of the same user, handlers of different users run in parallel.
+// In cluster mode the handlers on other nodes are serialized by a distributed lock as well.
+type topicLocker struct {
+	bot_id  int64
+	stripes [topicLockStripes]sync.Mutex
+	held    sync.Map // user_id -> unlock func of the distributed lock
+}
+
+func (l *topicLocker) stripe(user_id int64) *sync.Mutex {
+	return &l.stripes[uint64(user_id)%topicLockStripes]
+}
+
+// Lock locks the user, Unlock must be called only if no error is returned.
+// The handler must not run if the distributed lock is unavailable, the other nodes may be in the critical section.
+func (l *topicLocker) Lock(user_id int64) error {
+	l.stripe(user_id).Lock()
+
+	if !cluster.Enabled() {
+		return nil
+	}
+
+	unlock, err := cluster.Lock(fmt.Sprintf("topic:%d:%d", l.bot_id, user_id))
+	if err != nil {
+		l.stripe(user_id).Unlock()
+		clog.Errorf("[Bot %d] failed to acquire lock of user %d, error: %s", l.bot_id, user_id, err)
+		return err
+	}
+
+	l.held.Store(user_id, unlock)
+	return nil
+}
+
+func (l *topicLocker) Unlock(user_id int64) {
+	if unlock, ok := l.held.LoadAndDelete(user_id); ok {
+		unlock.(func())()
+	}
+
+	l.stripe(user_id).Unlock()
+}
+
+// findTopicByThread finds the topic of the forum thread and locks its user, the returned unlock must always be called.
+func (bot *Bot) findTopicByThread(thread_id int) (model.Topic, func(), error) {
+	var topic model.Topic
+	err := DB().Where("bot_id", bot.Self.ID).Where("topic_id", thread_id).Find(&topic).Error
+	if err != nil || topic.Id == 0 {
+		return topic, func() {}, err
+	}
+
+	user_id := topic.UserId
+	err = bot.topics.Lock(user_id)
+	if err != nil {
+		return model.Topic{}, func() {}, err
+	}
+	unlock := func() {
+		bot.topics.Unlock(user_id)
+	}
+
+	// Reload, the topic may be changed before the lock is held
+	topic = model.Topic{}
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return topic, unlock, err
+	}
+
+	if topic.TopicId != thread_id {
+		return model.Topic{}, unlock, nil
+	}
+
+	return topic, unlock, nil
+}
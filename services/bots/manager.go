@@ -0,0 +1,497 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"Topicgram/services/cluster"
+	"Topicgram/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/gin-gonic/gin"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const managerSyncInterval = 30 * time.Second
+
+var manager *Manager
+
+type Manager struct {
+	*model.ManagerConfig
+	*BotAPI
+
+	secretToken string
+	stopPolling context.CancelFunc
+	registry    sync.Mutex
+}
+
+func LoadManager(managerConfig *model.ManagerConfig) error {
+	b, err := botapi.NewBotAPIWithClient(managerConfig.Token, botapi.APIEndpoint, utils.BotClient)
+	if err != nil {
+		return err
+	}
+
+	secretToken := utils.MD5(managerConfig.WebHook.Host) + utils.SHA256(managerConfig.Token)
+
+	switch managerConfig.Mode {
+	case model.BotModePolling:
+		_, err = b.Request(botapi.DeleteWebhookConfig{})
+	default:
+		_, err = b.Request(botapi.WebhookConfig{
+			URL: &url.URL{
+				Scheme: "https",
+				Host:   managerConfig.WebHook.Host,
+				Path:   "/topicgram/manager",
+			},
+			MaxConnections: 100,
+			SecretToken:    secretToken,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	manager = &Manager{ManagerConfig: managerConfig, BotAPI: NewBotAPI(b), secretToken: secretToken}
+
+	if managerConfig.Mode == model.BotModePolling {
+		ctx, cancel := context.WithCancel(context.Background())
+		manager.stopPolling = cancel
+		go manager.poll(ctx, manager.handleUpdate)
+	}
+
+	clog.Successf("[Manager %d] Load completed", b.Self.ID)
+
+	manager.loadBots()
+
+	if cluster.Enabled() {
+		go manager.syncBots()
+	}
+	return nil
+}
+
+func (manager *Manager) loadBots() {
+	var managedBots []model.ManagedBot
+	err := DB().Where("is_paused", false).Find(&managedBots).Error
+	if err != nil {
+		clog.Errorf("[Manager %d] failed to query bots, error: %s", manager.Self.ID, err)
+		return
+	}
+
+	for i := range managedBots {
+		_, err := manager.loadBot(&managedBots[i])
+		if err != nil {
+			clog.Errorf("[Manager %d] failed to load bot %d, error: %s", manager.Self.ID, managedBots[i].BotId, err)
+		}
+	}
+}
+
+// syncBots follows the bots registered, paused or deleted on other nodes in cluster mode.
+func (manager *Manager) syncBots() {
+	for range time.Tick(managerSyncInterval) {
+		manager.sync()
+	}
+}
+
+func (manager *Manager) sync() {
+	manager.registry.Lock()
+	defer manager.registry.Unlock()
+
+	var managedBots []model.ManagedBot
+	err := DB().Where("is_paused", false).Find(&managedBots).Error
+	if err != nil {
+		clog.Errorf("[Manager %d] failed to query bots, error: %s", manager.Self.ID, err)
+		return
+	}
+
+	active := make(map[int64]bool, len(managedBots))
+	for i := range managedBots {
+		managedBot := &managedBots[i]
+		active[managedBot.BotId] = true
+
+		token, err := utils.Decrypt(manager.SecretKey, managedBot.Token)
+		if err != nil {
+			continue
+		}
+
+		bot, ok := Get(managedBot.BotId)
+		if ok && bot.Token == token {
+			continue
+		}
+
+		if ok {
+			_, err = manager.reloadBot(managedBot)
+		} else {
+			_, err = manager.loadBot(managedBot)
+		}
+		if err != nil {
+			clog.Errorf("[Manager %d] failed to load bot %d, error: %s", manager.Self.ID, managedBot.BotId, err)
+		}
+	}
+
+	botsMu.RLock()
+	var inactive []int64
+	for bot_id, bot := range bots {
+		if bot.managed && !active[bot_id] {
+			inactive = append(inactive, bot_id)
+		}
+	}
+	botsMu.RUnlock()
+
+	for _, bot_id := range inactive {
+		unregister(bot_id)
+	}
+}
+
+func (manager *Manager) botConfig(managedBot *model.ManagedBot, token string) *model.BotConfig {
+	botConfig := &model.BotConfig{
+		Token:        token,
+		GroupId:      managedBot.GroupId,
+		LanguageCode: managedBot.LanguageCode,
+		Mode:         manager.Mode,
+		Archive:      manager.Archive,
+	}
+	botConfig.WebHook.Host = manager.WebHook.Host
+
+	return botConfig
+}
+
+func (manager *Manager) loadBot(managedBot *model.ManagedBot) (*Bot, error) {
+	bot, err := manager.newManagedBot(managedBot)
+	if err != nil {
+		return nil, err
+	}
+
+	return bot, register(bot)
+}
+
+// reloadBot validates the token of the bot and swaps the running instance, the running instance is kept on error.
+func (manager *Manager) reloadBot(managedBot *model.ManagedBot) (*Bot, error) {
+	bot, err := manager.newManagedBot(managedBot)
+	if err != nil {
+		return nil, err
+	}
+
+	swap(bot)
+	return bot, nil
+}
+
+// newManagedBot creates the instance of the managed bot without serving it.
+func (manager *Manager) newManagedBot(managedBot *model.ManagedBot) (*Bot, error) {
+	token, err := utils.Decrypt(manager.SecretKey, managedBot.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := newBot(manager.botConfig(managedBot, token))
+	if err != nil {
+		return nil, err
+	}
+
+	if bot.Self.ID != managedBot.BotId {
+		return nil, fmt.Errorf("[Bot %d] Token mismatch", managedBot.BotId)
+	}
+
+	bot.managed = true
+	return bot, nil
+}
+
+func ManagerHookHandler(c *gin.Context) {
+	if manager == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return
+	}
+
+	token := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if token != manager.secretToken {
+		c.JSON(http.StatusForbidden, gin.H{"error": "bot not found"})
+		return
+	}
+
+	update, err := manager.HandleUpdate(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	go manager.handleUpdate(update)
+	c.String(200, "OK")
+}
+
+func (manager *Manager) handleUpdate(update *botapi.Update) {
+	defer Recover()
+
+	msg := update.Message
+	if msg == nil || msg.From == nil || !msg.Chat.IsPrivate() {
+		return
+	}
+
+	manager.handleNewMessage(msg)
+}
+
+func (manager *Manager) handleNewMessage(msg *botapi.Message) {
+	translator := i18n.GetOrDefault(msg.From.LanguageCode)
+
+	currentChatConfig := botapi.ChatConfig{
+		ChatID: msg.Chat.ID,
+	}
+	currentChat := botapi.BaseChat{
+		ChatConfig: currentChatConfig,
+		ReplyParameters: botapi.ReplyParameters{
+			AllowSendingWithoutReply: true,
+			MessageID:                msg.MessageID,
+		},
+	}
+	currentMessage := botapi.BaseChatMessage{
+		ChatConfig: currentChatConfig,
+		MessageID:  msg.MessageID,
+	}
+
+	if !strings.HasPrefix(msg.Text, "/") {
+		manager.sendManagerHelp(currentChat, translator)
+		return
+	}
+
+	command, args, _ := strings.Cut(msg.Text, " ")
+	fields := strings.Fields(args)
+
+	manager.registry.Lock()
+	defer manager.registry.Unlock()
+
+	switch command {
+	case "/start", "/help":
+		manager.sendManagerHelp(currentChat, translator)
+		return
+
+	case "/newbot":
+		// Never keep the token in chat history
+		manager.Request(botapi.DeleteMessageConfig{
+			BaseChatMessage: currentMessage,
+		})
+
+		if len(fields) != 2 {
+			manager.sendManagerHelp(currentChat, translator)
+			return
+		}
+
+		token := fields[0]
+		bot_id, ok := BotIdFromToken(token)
+		if !ok {
+			manager.sendManagerHelp(currentChat, translator)
+			return
+		}
+
+		group_id, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			manager.sendManagerHelp(currentChat, translator)
+			return
+		}
+
+		var managedBot model.ManagedBot
+		err = DB().Where("bot_id", bot_id).Find(&managedBot).Error
+		if err != nil {
+			manager.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		if _, loaded := Get(bot_id); loaded || managedBot.Id != 0 {
+			manager.sendManagerBotExists(currentChat, translator)
+			return
+		}
+
+		managedBot = model.ManagedBot{
+			BotId:        bot_id,
+			OwnerId:      msg.From.ID,
+			GroupId:      group_id,
+			LanguageCode: msg.From.LanguageCode,
+		}
+
+		bot, err := newBot(manager.botConfig(&managedBot, token))
+		if err != nil {
+			manager.sendManagerBotLoadFailed(currentChat, translator, err)
+			return
+		}
+
+		owner, err := bot.GetChatMember(botapi.GetChatMemberConfig{
+			ChatConfigWithUser: botapi.ChatConfigWithUser{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: group_id,
+				},
+				UserID: msg.From.ID,
+			},
+		})
+		if err != nil || (owner.Status != "creator" && owner.Status != "administrator") {
+			bot.Request(botapi.DeleteWebhookConfig{})
+			manager.sendManagerBotLoadFailed(currentChat, translator, fmt.Errorf("[Group %d] Owner must be a group administrator", group_id))
+			return
+		}
+
+		managedBot.UserName = bot.Self.UserName
+		managedBot.Token, err = utils.Encrypt(manager.SecretKey, token)
+		if err != nil {
+			bot.Request(botapi.DeleteWebhookConfig{})
+			manager.sendError(currentChat, translator)
+			return
+		}
+
+		err = DB().Create(&managedBot).Error
+		if err != nil {
+			bot.Request(botapi.DeleteWebhookConfig{})
+			manager.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		err = register(bot)
+		if err != nil {
+			manager.sendManagerBotLoadFailed(currentChat, translator, err)
+			return
+		}
+
+		manager.sendManagerBotAdded(currentChat, translator, bot.Self.UserName)
+		return
+
+	case "/mybots":
+		var managedBots []model.ManagedBot
+		err := DB().Where("owner_id", msg.From.ID).Find(&managedBots).Error
+		if err != nil {
+			manager.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		manager.sendManagerBots(currentChat, translator, managedBots)
+		return
+
+	case "/pause", "/resume", "/delete", "/retoken":
+		if command == "/retoken" {
+			// Never keep the token in chat history
+			manager.Request(botapi.DeleteMessageConfig{
+				BaseChatMessage: currentMessage,
+			})
+		}
+
+		if len(fields) < 1 {
+			manager.sendManagerHelp(currentChat, translator)
+			return
+		}
+
+		bot_id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			manager.sendManagerHelp(currentChat, translator)
+			return
+		}
+
+		var managedBot model.ManagedBot
+		err = DB().Where("bot_id", bot_id).Where("owner_id", msg.From.ID).Find(&managedBot).Error
+		if err != nil {
+			manager.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		if managedBot.Id == 0 {
+			manager.sendManagerBotNotFound(currentChat, translator)
+			return
+		}
+
+		switch command {
+		case "/pause":
+			if managedBot.IsPaused {
+				break
+			}
+
+			Unload(managedBot.BotId)
+
+			managedBot.IsPaused = true
+			err = DB().Save(&managedBot).Error
+			if err != nil {
+				manager.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+		case "/resume":
+			if !managedBot.IsPaused {
+				break
+			}
+
+			_, err = manager.loadBot(&managedBot)
+			if err != nil {
+				manager.sendManagerBotLoadFailed(currentChat, translator, err)
+				return
+			}
+
+			managedBot.IsPaused = false
+			err = DB().Save(&managedBot).Error
+			if err != nil {
+				manager.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+		case "/delete":
+			Unload(managedBot.BotId)
+
+			err = DB().Delete(&managedBot).Error
+			if err != nil {
+				manager.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+
+			DB().Model(model.Msg{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Topic{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Offset{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Archive{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Appeal{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Audit{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Snippet{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Setting{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Member{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.Event{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.MediaGroup{}).Where("bot_id", managedBot.BotId).Delete(nil)
+			DB().Model(model.MediaGroupItem{}).Where("bot_id", managedBot.BotId).Delete(nil)
+
+		case "/retoken":
+			if len(fields) != 2 {
+				manager.sendManagerHelp(currentChat, translator)
+				return
+			}
+
+			token := fields[1]
+			if id, ok := BotIdFromToken(token); !ok || id != managedBot.BotId {
+				manager.sendManagerBotLoadFailed(currentChat, translator, fmt.Errorf("[Bot %d] Token mismatch", managedBot.BotId))
+				return
+			}
+
+			managedBot.Token, err = utils.Encrypt(manager.SecretKey, token)
+			if err != nil {
+				manager.sendError(currentChat, translator)
+				return
+			}
+
+			if !managedBot.IsPaused {
+				_, err = manager.reloadBot(&managedBot)
+				if err != nil {
+					manager.sendManagerBotLoadFailed(currentChat, translator, err)
+					return
+				}
+			}
+
+			err = DB().Save(&managedBot).Error
+			if err != nil {
+				manager.sendDatabaseError(currentChat, translator, err)
+				return
+			}
+		}
+
+		manager.sendSuccess(currentChat, translator)
+		return
+
+	default:
+		manager.sendUnknownCommand(currentChat, translator)
+		return
+	}
+}
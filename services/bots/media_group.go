@@ -1,6 +1,7 @@
 package bots
 
 import (
+	"Topicgram/services/cluster"
 	"sort"
 	"sync"
 	"time"
@@ -24,6 +25,7 @@ type MediaGroup struct {
 	sync.Mutex
 	Messages []*botapi.Message
 	done     chan struct{}
+	shared   bool // staged in database by all nodes
 }
 
 func (mediaGroup *MediaGroup) Add(msg *botapi.Message) {
@@ -58,30 +60,52 @@ func (mediaGroup *MediaGroup) Sort() {
 	})
 }
 
-func (bot *Bot) getMediaGroup(msg *botapi.Message) (*MediaGroup, bool) {
+// collectMediaGroup groups the messages of an album before they are dispatched, it returns false if the message is merged into a pending album.
+// The first message of an album is dispatched once ready is closed, so the dispatcher workers never wait for the album.
+func (bot *Bot) collectMediaGroup(msg *botapi.Message) (ready <-chan struct{}, ok bool) {
 	if msg.MediaGroupID == "" {
-		return nil, false
+		return nil, true
+	}
+
+	if cluster.Enabled() {
+		return bot.collectSharedMediaGroup(msg)
 	}
 
-	mediaGroup := &MediaGroup{}
+	mediaGroup := &MediaGroup{done: make(chan struct{})}
 	if bot.mediaGroups.NotFoundAdd(msg.MediaGroupID, mediaGroupLifeSpan, mediaGroup) {
-		done := make(chan struct{})
-		mediaGroup.done = done
 		mediaGroup.Add(msg)
-
-		<-done
-		mediaGroup.Sort()
-		return mediaGroup, false
+		bot.pendingMediaGroups.Store(msg, mediaGroup)
+		return mediaGroup.done, true
 	}
 
 	item, err := bot.mediaGroups.Value(msg.MediaGroupID)
 	if err == nil {
 		mediaGroup := item.Data()
 		mediaGroup.Add(msg)
-		return nil, true
+		return nil, false
+	}
+
+	return nil, true
+}
+
+// getMediaGroup returns the collected album, it returns nil if the message is not the first message of an album.
+func (bot *Bot) getMediaGroup(msg *botapi.Message) *MediaGroup {
+	if msg.MediaGroupID == "" {
+		return nil
+	}
+
+	value, ok := bot.pendingMediaGroups.LoadAndDelete(msg)
+	if !ok {
+		return nil
+	}
+
+	mediaGroup := value.(*MediaGroup)
+	if mediaGroup.shared {
+		return bot.loadSharedMediaGroup(msg)
 	}
 
-	return nil, false
+	mediaGroup.Sort()
+	return mediaGroup
 }
 
 func generateMediaGroup(msgs []*botapi.Message, baseChat botapi.BaseChat) (botapi.MediaGroupConfig, bool) {
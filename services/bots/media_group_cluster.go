@@ -0,0 +1,75 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"encoding/json"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+// collectSharedMediaGroup stages the messages of an album in database, the messages of an album may be received by different nodes.
+// The node which claims the album relays it, it returns false if the album is claimed by another node.
+func (bot *Bot) collectSharedMediaGroup(msg *botapi.Message) (<-chan struct{}, bool) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to marshal message, error: %s", bot.Self.ID, err)
+		return nil, true
+	}
+
+	err = DB().Create(&model.MediaGroupItem{
+		BotId:        bot.Self.ID,
+		MediaGroupId: msg.MediaGroupID,
+		MessageId:    msg.MessageID,
+		Message:      string(data),
+	}).Error
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to stage message, error: %s", bot.Self.ID, err)
+		return nil, true
+	}
+
+	err = DB().Create(&model.MediaGroup{
+		BotId:        bot.Self.ID,
+		MediaGroupId: msg.MediaGroupID,
+	}).Error
+	if err != nil {
+		return nil, false
+	}
+
+	// The other messages are staged by any node within the life span
+	ready := make(chan struct{})
+	time.AfterFunc(mediaGroupLifeSpan, func() {
+		close(ready)
+	})
+
+	bot.pendingMediaGroups.Store(msg, &MediaGroup{shared: true})
+	return ready, true
+}
+
+// loadSharedMediaGroup loads the staged messages of the album.
+func (bot *Bot) loadSharedMediaGroup(msg *botapi.Message) *MediaGroup {
+	var items []model.MediaGroupItem
+	err := DB().Where("bot_id", bot.Self.ID).Where("media_group_id", msg.MediaGroupID).Order("message_id").Find(&items).Error
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to load media group, error: %s", bot.Self.ID, err)
+		return &MediaGroup{Messages: []*botapi.Message{msg}}
+	}
+
+	mediaGroup := &MediaGroup{}
+	for _, item := range items {
+		var message botapi.Message
+		if err := json.Unmarshal([]byte(item.Message), &message); err != nil {
+			continue
+		}
+
+		mediaGroup.Add(&message)
+	}
+
+	if len(mediaGroup.Messages) == 0 {
+		mediaGroup.Add(msg)
+	}
+
+	return mediaGroup
+}
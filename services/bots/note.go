@@ -0,0 +1,82 @@
+package bots
+
+import (
+	"Topicgram/model"
+	"strings"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const NOTE_REACTION = "✍"
+
+// noteContent returns the content of the internal note, ok is false if the message is not a note.
+func (bot *Bot) noteContent(msg *botapi.Message) (string, bool) {
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	command, args, _ := strings.Cut(text, " ")
+	if command == "/note" || command == "/note@"+bot.Self.UserName {
+		return strings.TrimSpace(args), true
+	}
+
+	if bot.Notes.Prefix != "" && strings.HasPrefix(text, bot.Notes.Prefix) {
+		return strings.TrimSpace(strings.TrimPrefix(text, bot.Notes.Prefix)), true
+	}
+
+	return "", false
+}
+
+// isNote reports whether the message or any message of its album is an internal note.
+func (bot *Bot) isNote(msg *botapi.Message, mediaGroup *MediaGroup) bool {
+	if _, ok := bot.noteContent(msg); ok {
+		return true
+	}
+
+	if mediaGroup == nil {
+		return false
+	}
+
+	for _, msg := range mediaGroup.Messages {
+		if _, ok := bot.noteContent(msg); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keepNote marks the internal note in the topic and records it, the note is never sent to the user.
+func (bot *Bot) keepNote(topic *model.Topic, msg *botapi.Message, mediaGroup *MediaGroup) {
+	messages := []*botapi.Message{msg}
+	if mediaGroup != nil {
+		messages = mediaGroup.Messages
+	}
+
+	var content []string
+	msgs := make([]model.Msg, 0, len(messages))
+	for _, msg := range messages {
+		if text, ok := bot.noteContent(msg); ok && text != "" {
+			content = append(content, text)
+		}
+
+		msgs = append(msgs, model.Msg{
+			BotId:      bot.Self.ID,
+			TopicId:    topic.Id,
+			TopicMsgId: msg.MessageID,
+		})
+	}
+
+	bot.Request(botapi.SetMessageReactionConfig{
+		BaseChatMessage: botapi.BaseChatMessage{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: msg.Chat.ID,
+			},
+			MessageID: msg.MessageID,
+		},
+		Reaction: []botapi.ReactionType{{Type: "emoji", Emoji: NOTE_REACTION}},
+	})
+
+	bot.archive(model.DirectionNote, topic, messages, msgs)
+	bot.audit(msg.From.ID, topic.UserId, model.AuditNote, strings.Join(content, "\n"))
+}
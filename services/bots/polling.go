@@ -0,0 +1,66 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"context"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const (
+	pollingTimeout    = 50
+	pollingRetryDelay = 3 * time.Second
+)
+
+// poll fetches the updates until the context is done.
+// handle must return only after the update is handled, enqueued or collected into an album, the offset is saved after it.
+func (bot *BotAPI) poll(ctx context.Context, handle func(update *botapi.Update)) {
+	var offset model.Offset
+	err := DB().Where("bot_id", bot.Self.ID).Find(&offset).Error
+	if err != nil {
+		clog.Errorf("[Bot %d] failed to load update offset, error: %s", bot.Self.ID, err)
+	}
+	offset.BotId = bot.Self.ID
+
+	clog.Infof("[Bot %d] Polling updates from offset %d", bot.Self.ID, offset.UpdateId)
+
+	for {
+		updates, err := bot.GetUpdatesWithContext(ctx, botapi.UpdateConfig{
+			Offset:  offset.UpdateId,
+			Timeout: pollingTimeout,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			clog.Errorf("[Bot %d] getUpdates failed, error: %s", bot.Self.ID, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollingRetryDelay):
+			}
+			continue
+		}
+
+		if len(updates) == 0 {
+			continue
+		}
+
+		for i := range updates {
+			update := &updates[i]
+			offset.UpdateId = update.UpdateID + 1
+
+			handle(update)
+		}
+
+		err = DB().Save(&offset).Error
+		if err != nil {
+			clog.Errorf("[Bot %d] failed to save update offset, error: %s", bot.Self.ID, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"strings"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const ROLE_CACHE_DURATION = time.Minute
+
+// commandRoles is the role required by the group commands.
+var commandRoles = map[string]model.Role{
+	"/ban":       model.RoleModerator,
+	"/unban":     model.RoleModerator,
+	"/terminate": model.RoleModerator,
+	"/search":    model.RoleAgent,
+	"/export":    model.RoleAgent,
+	"/note":      model.RoleAgent,
+	"/s":         model.RoleAgent,
+	"/snippet":   model.RoleModerator,
+	"/role":      model.RoleOwner,
+	"/audit":     model.RoleModerator,
+	"/away":      model.RoleModerator,
+	"/resolve":   model.RoleAgent,
+	"/pending":   model.RoleAgent,
+	"/tickets":   model.RoleAgent,
+}
+
+type cachedRole struct {
+	role      model.Role
+	expiresAt time.Time
+}
+
+func (bot *Bot) defaultRole() model.Role {
+	role, ok := model.ParseRole(bot.Roles.Default)
+	if !ok {
+		return model.RoleObserver
+	}
+	return role
+}
+
+// roleOf returns the role of the group member.
+// The creator is always the owner, otherwise the assigned role is used, or it is derived from the admin rights.
+func (bot *Bot) roleOf(user_id int64) model.Role {
+	if cached, ok := bot.roles.Load(user_id); ok && time.Now().Before(cached.(cachedRole).expiresAt) {
+		return cached.(cachedRole).role
+	}
+
+	member, err := bot.GetChatMember(botapi.GetChatMemberConfig{
+		ChatConfigWithUser: botapi.ChatConfigWithUser{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+			UserID: user_id,
+		},
+	})
+	if err != nil {
+		return model.RoleObserver
+	}
+
+	var assigned model.Member
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&assigned).Error
+	if err != nil {
+		return model.RoleObserver
+	}
+
+	var role model.Role
+	switch {
+	case member.Status == "creator":
+		role = model.RoleOwner
+	case assigned.UserId != 0:
+		role = assigned.Role
+	case member.Status == "administrator":
+		role = model.RoleModerator
+	case member.Status == "member", member.Status == "restricted":
+		role = bot.defaultRole()
+	default:
+		role = model.RoleObserver
+	}
+
+	bot.roles.Store(user_id, cachedRole{role: role, expiresAt: time.Now().Add(ROLE_CACHE_DURATION)})
+	return role
+}
+
+// hasRole reports whether the sender of the group message has the role.
+func (bot *Bot) hasRole(msg *botapi.Message, role model.Role) bool {
+	// Anonymous administrators
+	if msg.SenderChat != nil && msg.SenderChat.ID == bot.GroupId {
+		return model.RoleModerator >= role
+	}
+
+	return bot.roleOf(msg.From.ID) >= role
+}
+
+// canRunCommand reports whether the sender of the group message may run the command.
+func (bot *Bot) canRunCommand(msg *botapi.Message, command string) bool {
+	role, ok := commandRoles[strings.TrimSuffix(command, "@"+bot.Self.UserName)]
+	if !ok {
+		return true
+	}
+
+	return bot.hasRole(msg, role)
+}
+
+// assignRole assigns the role to the member, nil removes the assigned role.
+func (bot *Bot) assignRole(user_id int64, role *model.Role) error {
+	defer bot.roles.Delete(user_id)
+
+	if role == nil {
+		return DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Delete(&model.Member{}).Error
+	}
+
+	return DB().Save(&model.Member{
+		BotId:  bot.Self.ID,
+		UserId: user_id,
+		Role:   *role,
+	}).Error
+}
@@ -2,7 +2,9 @@ package bots
 
 import (
 	"Topicgram/i18n"
-	"slices"
+	"Topicgram/model"
+	"Topicgram/services/captcha"
+	"time"
 
 	botapi "github.com/OvyFlash/telegram-bot-api"
 	formatter "gitlab.com/CoiaPrant/telegram-bot-formatter"
@@ -32,15 +34,9 @@ func (bot *Bot) sendWelcome(baseChat botapi.BaseChat, translator i18n.Translator
 	return err
 }
 
-func (bot *BotAPI) sendCaptcha(baseChat botapi.BaseChat, translator i18n.Translator, description *formatter.Builder, replyMarkup botapi.InlineKeyboardMarkup) error {
-	baseChat.ReplyMarkup = replyMarkup
-
+func (bot *BotAPI) sendCaptcha(baseChat botapi.BaseChat, translator i18n.Translator, challenge captcha.Challenge, puzzle captcha.Puzzle, description *formatter.Builder) error {
 	text, entities := translator.Captcha(description)
-	_, err := bot.Send(botapi.MessageConfig{
-		BaseChat: baseChat,
-		Text:     text,
-		Entities: entities,
-	})
+	_, err := bot.Send(challenge.Render(baseChat, puzzle, text, entities))
 	return err
 }
 
@@ -78,8 +74,8 @@ func (bot *BotAPI) sendCaptchaCompleted(baseChat botapi.BaseChat, translator i18
 	return err
 }
 
-func (bot *BotAPI) sendBanned(baseChat botapi.BaseChat, translator i18n.Translator) error {
-	text, entities := translator.Banned()
+func (bot *BotAPI) sendBanned(baseChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic) error {
+	text, entities := translator.Banned(topic.BanReason, topic.BanExpiry())
 	_, err := bot.Send(botapi.MessageConfig{
 		BaseChat: baseChat,
 		Text:     text,
@@ -108,49 +104,28 @@ func (bot *BotAPI) sendTerminated(baseChat botapi.BaseChat, translator i18n.Tran
 	return err
 }
 
-func (bot *BotAPI) sendSender(baseChat botapi.BaseChat, translator i18n.Translator, user *botapi.User) (botapi.Message, error) {
-	chat, err := bot.GetChat(botapi.ChatInfoConfig{
-		ChatConfig: botapi.ChatConfig{
-			ChatID: user.ID,
-		},
-	})
+func (bot *BotAPI) sendSender(baseChat botapi.BaseChat, translator i18n.Translator, user *botapi.User, topic *model.Topic) (botapi.Message, error) {
+	card, err := bot.senderCard(translator, user, topic)
 	if err != nil {
 		return botapi.Message{}, err
 	}
 
-	text, entities, markup := translator.Sender(user, &chat)
-	baseChat.ReplyMarkup = markup
-	if chat.Photo != nil {
-		photos, err := bot.GetUserProfilePhotos(botapi.UserProfilePhotosConfig{
-			UserID: user.ID,
-			Limit:  1,
-		})
-		if err != nil {
-			goto textOnly
-		}
-
-		if len(photos.Photos) < 1 || len(photos.Photos[0]) < 1 {
-			goto textOnly
-		}
-
-		photo := slices.MaxFunc(photos.Photos[0], func(a, b botapi.PhotoSize) int {
-			return b.FileSize - a.FileSize
-		})
+	baseChat.ReplyMarkup = card.markup
+	if card.photo != "" {
 		return bot.Send(botapi.PhotoConfig{
 			BaseFile: botapi.BaseFile{
 				BaseChat: baseChat,
-				File:     botapi.FileID(photo.FileID),
+				File:     botapi.FileID(card.photo),
 			},
-			Caption:         text,
-			CaptionEntities: entities,
+			Caption:         card.text,
+			CaptionEntities: card.entities,
 		})
 	}
 
-textOnly:
 	return bot.Send(botapi.MessageConfig{
 		BaseChat: baseChat,
-		Text:     text,
-		Entities: entities,
+		Text:     card.text,
+		Entities: card.entities,
 	})
 }
 
@@ -178,8 +153,8 @@ func (bot *BotAPI) sendCaptchaCompletedNotify(baseChat botapi.BaseChat, translat
 	return err
 }
 
-func (bot *BotAPI) sendBanUser(baseChat botapi.BaseChat, translator i18n.Translator, user_id int64) error {
-	text, entities := translator.BanUser(user_id)
+func (bot *BotAPI) sendBanUser(baseChat botapi.BaseChat, translator i18n.Translator, topic *model.Topic) error {
+	text, entities := translator.BanUser(topic.UserId, topic.BanReason, topic.BanExpiry())
 	_, err := bot.Send(botapi.MessageConfig{
 		BaseChat: baseChat,
 		Text:     text,
@@ -197,3 +172,191 @@ func (bot *BotAPI) sendUnbanUser(baseChat botapi.BaseChat, translator i18n.Trans
 	})
 	return err
 }
+
+func (bot *BotAPI) sendSearchResults(baseChat botapi.BaseChat, translator i18n.Translator, group_id int64, archives []model.Archive) error {
+	text, entities := translator.SearchResults(group_id, archives)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+		LinkPreviewOptions: botapi.LinkPreviewOptions{
+			IsDisabled: true,
+		},
+	})
+	return err
+}
+
+func (bot *BotAPI) sendFile(baseChat botapi.BaseChat, name string, data []byte) error {
+	_, err := bot.Send(botapi.DocumentConfig{
+		BaseFile: botapi.BaseFile{
+			BaseChat: baseChat,
+			File: botapi.FileBytes{
+				Name:  name,
+				Bytes: data,
+			},
+		},
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCaptchaWrongAnswer(baseChat botapi.BaseChat, translator i18n.Translator, remaining int) error {
+	text, entities := translator.CaptchaWrongAnswer(remaining)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCaptchaCooldown(baseChat botapi.BaseChat, translator i18n.Translator, wait time.Duration) error {
+	text, entities := translator.CaptchaCooldown(wait)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCaptchaAutoBanned(baseChat botapi.BaseChat, translator i18n.Translator, user_id int64, failures int) error {
+	text, entities := translator.CaptchaAutoBanned(user_id, failures)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendBanExpired(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.BanExpired()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendBanExpiredNotify(baseChat botapi.BaseChat, translator i18n.Translator, user_id int64) error {
+	text, entities := translator.BanExpiredNotify(user_id)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendAppealSent(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.AppealSent()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendAppealCooldown(baseChat botapi.BaseChat, translator i18n.Translator, wait time.Duration) error {
+	text, entities := translator.AppealCooldown(wait)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendAppealApproved(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.AppealApproved()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendAppealRejected(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.AppealRejected()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendRole(baseChat botapi.BaseChat, translator i18n.Translator, user_id int64, role model.Role) error {
+	text, entities := translator.Role(user_id, role)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendAuditLog(baseChat botapi.BaseChat, translator i18n.Translator, audits []model.Audit) error {
+	text, entities := translator.AuditLog(audits)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendSnippets(baseChat botapi.BaseChat, translator i18n.Translator, snippets []model.Snippet) error {
+	text, entities := translator.Snippets(snippets)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendSnippetNotFound(baseChat botapi.BaseChat, translator i18n.Translator, name string) error {
+	text, entities := translator.SnippetNotFound(name)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendFAQAnswer(baseChat botapi.BaseChat, translator i18n.Translator, answer string) error {
+	baseChat.ReplyMarkup = faqMarkup(translator)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     answer,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendAway(baseChat botapi.BaseChat, translator i18n.Translator, next time.Time) error {
+	text, entities := translator.Away(next)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendTickets(baseChat botapi.BaseChat, translator i18n.Translator, group_id int64, topics []model.Topic) error {
+	text, entities := translator.Tickets(group_id, topics)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+		LinkPreviewOptions: botapi.LinkPreviewOptions{
+			IsDisabled: true,
+		},
+	})
+	return err
+}
@@ -106,3 +106,13 @@ func (bot *BotAPI) sendFailedToEdit(baseChat botapi.BaseChat, translator i18n.Tr
 	})
 	return err
 }
+
+func (bot *BotAPI) sendPermissionDenied(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.Error_PermissionDenied()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
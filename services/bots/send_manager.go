@@ -0,0 +1,68 @@
+package bots
+
+import (
+	"Topicgram/i18n"
+	"Topicgram/model"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+func (bot *BotAPI) sendManagerHelp(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.Manager_Help()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendManagerBots(baseChat botapi.BaseChat, translator i18n.Translator, managedBots []model.ManagedBot) error {
+	text, entities := translator.Manager_Bots(managedBots)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendManagerBotAdded(baseChat botapi.BaseChat, translator i18n.Translator, username string) error {
+	text, entities := translator.Manager_BotAdded(username)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendManagerBotExists(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.Manager_BotExists()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendManagerBotNotFound(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.Manager_BotNotFound()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendManagerBotLoadFailed(baseChat botapi.BaseChat, translator i18n.Translator, e error) error {
+	text, entities := translator.Manager_BotLoadFailed(e)
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
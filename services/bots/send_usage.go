@@ -35,3 +35,83 @@ func (bot *BotAPI) sendCommandUsageTerminate(baseChat botapi.BaseChat, translato
 	})
 	return err
 }
+
+func (bot *BotAPI) sendCommandUsageSearch(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Search()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageExport(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Export()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageAppeal(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Appeal()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageRole(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Role()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageAudit(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Audit()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageAway(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Away()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageTickets(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Tickets()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+func (bot *BotAPI) sendCommandUsageSnippet(baseChat botapi.BaseChat, translator i18n.Translator) error {
+	text, entities := translator.CommandUsage_Snippet()
+	_, err := bot.Send(botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
@@ -0,0 +1,263 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"slices"
+	"strconv"
+	"strings"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const (
+	senderCallbackPrefix    = "sender:"
+	senderCallbackBan       = "ban"
+	senderCallbackUnban     = "unban"
+	senderCallbackTerminate = "terminate"
+	senderCallbackRefresh   = "refresh"
+	senderCallbackMute      = "mute"
+)
+
+type senderCard struct {
+	text     string
+	entities []botapi.MessageEntity
+	markup   botapi.InlineKeyboardMarkup
+	photo    string // file id of the profile photo
+}
+
+// senderCard renders the user info card with the admin action buttons.
+func (bot *BotAPI) senderCard(translator i18n.Translator, user *botapi.User, topic *model.Topic) (senderCard, error) {
+	chat, err := bot.GetChat(botapi.ChatInfoConfig{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: user.ID,
+		},
+	})
+	if err != nil {
+		return senderCard{}, err
+	}
+
+	var card senderCard
+	var markup any
+	card.text, card.entities, markup = translator.Sender(user, &chat, topic)
+	card.markup = senderMarkup(translator, markup, topic)
+
+	if chat.Photo != nil {
+		photos, err := bot.GetUserProfilePhotos(botapi.UserProfilePhotosConfig{
+			UserID: user.ID,
+			Limit:  1,
+		})
+		if err == nil && len(photos.Photos) > 0 && len(photos.Photos[0]) > 0 {
+			photo := slices.MaxFunc(photos.Photos[0], func(a, b botapi.PhotoSize) int {
+				return b.FileSize - a.FileSize
+			})
+			card.photo = photo.FileID
+		}
+	}
+
+	return card, nil
+}
+
+// senderMarkup appends the admin action buttons to the markup of the translator.
+func senderMarkup(translator i18n.Translator, markup any, topic *model.Topic) botapi.InlineKeyboardMarkup {
+	var rows [][]botapi.InlineKeyboardButton
+	switch markup := markup.(type) {
+	case botapi.InlineKeyboardMarkup:
+		rows = markup.InlineKeyboard
+	case *botapi.InlineKeyboardMarkup:
+		if markup != nil {
+			rows = markup.InlineKeyboard
+		}
+	}
+
+	id := strconv.FormatInt(topic.UserId, 10)
+	button := func(text, action string) botapi.InlineKeyboardButton {
+		return botapi.NewInlineKeyboardButtonData(text, senderCallbackPrefix+action+":"+id)
+	}
+
+	moderation := button(translator.SenderBan(), senderCallbackBan)
+	if topic.IsBan {
+		moderation = button(translator.SenderUnban(), senderCallbackUnban)
+	}
+
+	rows = append(rows,
+		botapi.NewInlineKeyboardRow(moderation, button(translator.SenderTerminate(), senderCallbackTerminate)),
+		botapi.NewInlineKeyboardRow(button(translator.SenderRefresh(), senderCallbackRefresh), button(translator.SenderMute(topic.IsMuted), senderCallbackMute)),
+	)
+	return botapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// updateSender edits the sender card in place, the forum topic is renamed as well.
+// The caller must hold the lock of the user.
+func (bot *Bot) updateSender(topic *model.Topic, message *botapi.Message) error {
+	chat, err := bot.GetChat(botapi.ChatInfoConfig{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	user := &botapi.User{
+		ID:        chat.ID,
+		FirstName: chat.FirstName,
+		LastName:  chat.LastName,
+		UserName:  chat.UserName,
+	}
+
+	card, err := bot.senderCard(i18n.GetOrDefault(bot.LanguageCode), user, topic)
+	if err != nil {
+		return err
+	}
+
+	baseEdit := botapi.BaseEdit{
+		BaseChatMessage: botapi.BaseChatMessage{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+			MessageID: message.MessageID,
+		},
+		ReplyMarkup: &card.markup,
+	}
+
+	// A text card can not become a photo card, and vice versa
+	switch {
+	case message.Photo != nil && card.photo != "":
+		_, err = bot.Send(botapi.EditMessageMediaConfig{
+			BaseEdit: baseEdit,
+			Media: &botapi.InputMediaPhoto{
+				BaseInputMedia: botapi.BaseInputMedia{
+					Type:            "photo",
+					Media:           botapi.FileID(card.photo),
+					Caption:         card.text,
+					CaptionEntities: card.entities,
+				},
+			},
+		})
+	case message.Photo != nil:
+		_, err = bot.Send(botapi.EditMessageCaptionConfig{
+			BaseEdit:        baseEdit,
+			Caption:         card.text,
+			CaptionEntities: card.entities,
+		})
+	default:
+		_, err = bot.Send(botapi.EditMessageTextConfig{
+			BaseEdit: baseEdit,
+			Text:     card.text,
+			Entities: card.entities,
+		})
+	}
+	if err != nil {
+		if err, ok := err.(*botapi.Error); !ok || !isNotModified(err) {
+			return err
+		}
+	}
+
+	if topic.TopicId != 0 {
+		bot.Request(botapi.EditForumTopicConfig{
+			BaseForum: botapi.BaseForum{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: bot.GroupId,
+				},
+				MessageThreadID: topic.TopicId,
+			},
+			Name: topicName(topic.State, user.FirstName, user.LastName),
+		})
+	}
+
+	return nil
+}
+
+// handleSenderCallback handles the admin action buttons of the sender card.
+func (bot *Bot) handleSenderCallback(callback *botapi.CallbackQuery, data string) {
+	translator := i18n.GetOrDefault(callback.From.LanguageCode)
+
+	action, id, _ := strings.Cut(data, ":")
+	user_id, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || callback.Message == nil {
+		bot.Request(botapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	if bot.roleOf(callback.From.ID) < model.RoleModerator {
+		text, _ := translator.Error_PermissionDenied()
+		bot.Request(botapi.NewCallbackWithAlert(callback.ID, text))
+		return
+	}
+
+	switch action {
+	case senderCallbackBan:
+		// The forum topic is deleted with the card
+		_, err = bot.Ban(user_id, callback.From.ID, 0, "")
+	case senderCallbackTerminate:
+		err = bot.Terminate(user_id, callback.From.ID)
+	case senderCallbackUnban:
+		_, err = bot.Unban(user_id, callback.From.ID)
+		if err == nil {
+			err = bot.refreshSender(user_id, callback.Message, false, callback.From.ID)
+		}
+	case senderCallbackMute:
+		err = bot.refreshSender(user_id, callback.Message, true, callback.From.ID)
+	case senderCallbackRefresh:
+		err = bot.refreshSender(user_id, callback.Message, false, callback.From.ID)
+	}
+	if err != nil {
+		text, _ := translator.Error()
+		bot.Request(botapi.NewCallbackWithAlert(callback.ID, text))
+		return
+	}
+
+	bot.Request(botapi.NewCallback(callback.ID, ""))
+}
+
+// refreshSender reloads the topic and edits the card, the notifications of the topic are toggled by the actor if mute is set.
+func (bot *Bot) refreshSender(user_id int64, message *botapi.Message, mute bool, actor int64) error {
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(user_id)
+	if err != nil {
+		return err
+	}
+	defer bot.topics.Unlock(user_id)
+
+	var topic model.Topic
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		return err
+	}
+
+	if topic.Id == 0 || topic.TopicId != message.MessageThreadID {
+		// The card is outdated, the conversation is over
+		bot.Request(botapi.DeleteMessageConfig{
+			BaseChatMessage: botapi.BaseChatMessage{
+				ChatConfig: botapi.ChatConfig{
+					ChatID: message.Chat.ID,
+				},
+				MessageID: message.MessageID,
+			},
+		})
+		return nil
+	}
+
+	if mute || topic.SenderMsgId != message.MessageID {
+		topic.IsMuted = topic.IsMuted != mute
+		topic.SenderMsgId = message.MessageID
+		err = saveTopic(&topic)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mute {
+		action := model.AuditUnmute
+		if topic.IsMuted {
+			action = model.AuditMute
+		}
+		bot.audit(actor, user_id, action, "")
+	}
+
+	return bot.updateSender(&topic, message)
+}
@@ -0,0 +1,225 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"Topicgram/utils"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"gorm.io/gorm"
+)
+
+var snippetNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// snippetTrigger returns the snippet name of a !name message.
+func snippetTrigger(text string) (string, bool) {
+	name, ok := strings.CutPrefix(text, "!")
+	if !ok {
+		return "", false
+	}
+
+	name = strings.ToLower(name)
+	return name, snippetNameRegexp.MatchString(name)
+}
+
+// handleSnippetCommand manages the snippets: /snippet add <name> [language_code] as a reply, /snippet list, /snippet del <name> [language_code].
+func (bot *Bot) handleSnippetCommand(currentChat botapi.BaseChat, translator i18n.Translator, msg *botapi.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) < 1 {
+		bot.sendCommandUsageSnippet(currentChat, translator)
+		return
+	}
+
+	switch fields[0] {
+	case "list":
+		var snippets []model.Snippet
+		err := DB().Where("bot_id", bot.Self.ID).Order("name").Order("language_code").Find(&snippets).Error
+		if err != nil {
+			bot.sendDatabaseError(currentChat, translator, err)
+			return
+		}
+
+		bot.sendSnippets(currentChat, translator, snippets)
+		return
+
+	case "add", "del":
+		if len(fields) < 2 || len(fields) > 3 {
+			bot.sendCommandUsageSnippet(currentChat, translator)
+			return
+		}
+	default:
+		bot.sendCommandUsageSnippet(currentChat, translator)
+		return
+	}
+
+	name := strings.ToLower(fields[1])
+	if !snippetNameRegexp.MatchString(name) {
+		bot.sendCommandUsageSnippet(currentChat, translator)
+		return
+	}
+
+	var languageCode string
+	if len(fields) == 3 {
+		languageCode = fields[2]
+	}
+
+	if fields[0] == "del" {
+		result := DB().Where("bot_id", bot.Self.ID).Where("name", name).Where("language_code", languageCode).Delete(&model.Snippet{})
+		if result.Error != nil {
+			bot.sendDatabaseError(currentChat, translator, result.Error)
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			bot.sendSnippetNotFound(currentChat, translator, name)
+			return
+		}
+
+		bot.sendSuccess(currentChat, translator)
+		return
+	}
+
+	// The content is taken from the replied message, the topic itself is not a reply
+	source := msg.ReplyToMessage
+	if source == nil || source.MessageID == msg.MessageThreadID || (source.Text == "" && source.Caption == "" && !hasSnippetMedia(source)) {
+		bot.sendCommandUsageSnippet(currentChat, translator)
+		return
+	}
+
+	snippet := newSnippet(source)
+	snippet.BotId = bot.Self.ID
+	snippet.Name = name
+	snippet.LanguageCode = languageCode
+	snippet.CreatedBy = msg.From.ID
+
+	// Replace the existing variant
+	err := DB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("bot_id", bot.Self.ID).Where("name", name).Where("language_code", languageCode).Delete(&model.Snippet{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&snippet).Error
+	})
+	if err != nil {
+		bot.sendDatabaseError(currentChat, translator, err)
+		return
+	}
+
+	bot.sendSuccess(currentChat, translator)
+}
+
+func hasSnippetMedia(msg *botapi.Message) bool {
+	switch mediaType, _ := messageMedia(msg); mediaType {
+	case "animation", "audio", "document", "photo", "video", "voice":
+		return true
+	default:
+		return false
+	}
+}
+
+func newSnippet(msg *botapi.Message) model.Snippet {
+	snippet := model.Snippet{
+		Content:  msg.Text,
+		Entities: "[]",
+	}
+
+	entities := msg.Entities
+	if msg.Text == "" {
+		snippet.Content = msg.Caption
+		entities = msg.CaptionEntities
+	}
+
+	if len(entities) != 0 {
+		data, err := json.Marshal(entities)
+		if err == nil {
+			snippet.Entities = string(data)
+		}
+	}
+
+	if hasSnippetMedia(msg) {
+		snippet.MediaType, snippet.FileId = messageMedia(msg)
+	}
+	return snippet
+}
+
+// findSnippet finds the variant of the snippet in the language, the default variant is the fallback.
+func (bot *Bot) findSnippet(name, languageCode string) (model.Snippet, error) {
+	var snippet model.Snippet
+	err := DB().Where("bot_id", bot.Self.ID).Where("name", name).Where("language_code IN ?", []string{languageCode, ""}).Order("language_code DESC").Limit(1).Find(&snippet).Error
+	return snippet, err
+}
+
+var errSnippetNotFound = errors.New("snippet not found")
+
+// sendSnippet sends the snippet to the user of the topic, the caller must hold the lock of the user.
+func (bot *Bot) sendSnippet(topic *model.Topic, name string, admin *botapi.User) error {
+	snippet, err := bot.findSnippet(name, topic.LanguageCode)
+	if err != nil {
+		return err
+	}
+
+	if snippet.Id == 0 {
+		return errSnippetNotFound
+	}
+
+	var entities []botapi.MessageEntity
+	json.Unmarshal([]byte(snippet.Entities), &entities)
+
+	text, entities := utils.ReplacePlaceholders(snippet.Content, entities, bot.snippetValues(topic, admin))
+
+	_, err = bot.reply(topic, func(userChat botapi.BaseChat) botapi.Chattable {
+		if snippet.MediaType == "" {
+			return botapi.MessageConfig{
+				BaseChat: userChat,
+				Text:     text,
+				Entities: entities,
+			}
+		}
+
+		baseFile := botapi.BaseFile{BaseChat: userChat, File: botapi.FileID(snippet.FileId)}
+		switch snippet.MediaType {
+		case "animation":
+			return botapi.AnimationConfig{BaseFile: baseFile, Caption: text, CaptionEntities: entities}
+		case "audio":
+			return botapi.AudioConfig{BaseFile: baseFile, Caption: text, CaptionEntities: entities}
+		case "photo":
+			return botapi.PhotoConfig{BaseFile: baseFile, Caption: text, CaptionEntities: entities}
+		case "video":
+			return botapi.VideoConfig{BaseFile: baseFile, Caption: text, CaptionEntities: entities}
+		case "voice":
+			return botapi.VoiceConfig{BaseFile: baseFile, Caption: text, CaptionEntities: entities}
+		default:
+			return botapi.DocumentConfig{BaseFile: baseFile, Caption: text, CaptionEntities: entities}
+		}
+	})
+	return err
+}
+
+// snippetValues returns the values of the placeholders.
+func (bot *Bot) snippetValues(topic *model.Topic, admin *botapi.User) map[string]string {
+	values := map[string]string{
+		"user_id":    strconv.FormatInt(topic.UserId, 10),
+		"admin_name": strings.TrimSpace(admin.FirstName + " " + admin.LastName),
+	}
+
+	chat, err := bot.GetChat(botapi.ChatInfoConfig{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	})
+	if err == nil {
+		values["first_name"] = chat.FirstName
+		values["last_name"] = chat.LastName
+		values["full_name"] = strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+		values["username"] = chat.UserName
+	}
+
+	return values
+}
@@ -0,0 +1,105 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"strings"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const TICKETS_LIMIT = 50
+
+var ticketPrefixes = map[model.TicketState]string{
+	model.TicketPending:  "⏳ ",
+	model.TicketResolved: "✅ ",
+}
+
+var ticketAudits = map[model.TicketState]model.AuditAction{
+	model.TicketOpen:     model.AuditTicketOpen,
+	model.TicketPending:  model.AuditTicketPending,
+	model.TicketResolved: model.AuditTicketResolved,
+}
+
+// topicName returns the name of the forum topic, prefixed by the ticket state.
+func topicName(state model.TicketState, firstName, lastName string) string {
+	return ticketPrefixes[state] + strings.TrimSpace(firstName+" "+lastName)
+}
+
+// renameTopic renames the forum topic after the ticket state.
+func (bot *Bot) renameTopic(topic *model.Topic) error {
+	chat, err := bot.GetChat(botapi.ChatInfoConfig{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = bot.Request(botapi.EditForumTopicConfig{
+		BaseForum: botapi.BaseForum{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+			MessageThreadID: topic.TopicId,
+		},
+		Name: topicName(topic.State, chat.FirstName, chat.LastName),
+	})
+	return err
+}
+
+// setTicketState moves the ticket to the state, the forum topic is closed when resolved and reopened when it leaves the resolved state.
+// The caller must hold the lock of the user.
+func (bot *Bot) setTicketState(topic *model.Topic, state model.TicketState, actor int64) error {
+	if topic.State == state {
+		return nil
+	}
+
+	previous := topic.State
+	topic.State = state
+	err := saveTopic(topic)
+	if err != nil {
+		topic.State = previous
+		return err
+	}
+
+	if topic.TopicId != 0 {
+		botTopic := botapi.BaseForum{
+			ChatConfig: botapi.ChatConfig{
+				ChatID: bot.GroupId,
+			},
+			MessageThreadID: topic.TopicId,
+		}
+
+		switch {
+		case state == model.TicketResolved:
+			bot.Request(botapi.CloseForumTopicConfig{
+				BaseForum: botTopic,
+			})
+		case previous == model.TicketResolved:
+			bot.Request(botapi.ReopenForumTopicConfig{
+				BaseForum: botTopic,
+			})
+		}
+
+		bot.renameTopic(topic)
+	}
+
+	bot.audit(actor, topic.UserId, ticketAudits[state], "")
+	return nil
+}
+
+// unresolvedTickets returns the topics which are not resolved, pending only lists the tickets waiting on the user.
+func (bot *Bot) unresolvedTickets(pending bool) ([]model.Topic, error) {
+	query := DB().Where("bot_id", bot.Self.ID).Where("topic_id != ?", 0).Where("is_ban", false)
+	if pending {
+		query = query.Where("state", model.TicketPending)
+	} else {
+		query = query.Where("state != ?", model.TicketResolved)
+	}
+
+	var topics []model.Topic
+	err := query.Order("id").Limit(TICKETS_LIMIT).Find(&topics).Error
+	return topics, err
+}
@@ -0,0 +1,132 @@
+package bots
+
+import (
+	. "Topicgram/database"
+	"Topicgram/i18n"
+	"Topicgram/model"
+	"Topicgram/services/captcha"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/gin-gonic/gin"
+)
+
+type webAppRequest struct {
+	InitData string `json:"init_data" binding:"required"`
+	Nonce    string `json:"nonce"`
+}
+
+func (bot *Bot) webAppURL() string {
+	return fmt.Sprintf("https://%s/topicgram/captcha/%d", bot.WebHook.Host, bot.Self.ID)
+}
+
+func WebAppPageHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", captcha.WebAppPage)
+}
+
+// webAppTopic validates the init data and finds the topic with a pending web app challange.
+func webAppTopic(c *gin.Context) (*Bot, webAppRequest, model.Topic, bool) {
+	var request webAppRequest
+	var topic model.Topic
+
+	bot_id, err := strconv.ParseInt(c.Param("botId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return nil, request, topic, false
+	}
+
+	bot, ok := Get(bot_id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bot not found"})
+		return nil, request, topic, false
+	}
+
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, request, topic, false
+	}
+
+	user_id, err := captcha.ValidateInitData(bot.Token, request.InitData)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return nil, request, topic, false
+	}
+
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, request, topic, false
+	}
+
+	if topic.Id == 0 || topic.IsBan || topic.Verification != model.VerificationNotCompleted || topic.ChallangeId == 0 || topic.ChallangeSent == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "challange not found"})
+		return nil, request, topic, false
+	}
+
+	if time.Now().After(time.Unix(topic.ChallangeSent, 0).Add(bot.captchaDuration())) {
+		c.JSON(http.StatusGone, gin.H{"error": "challange expired"})
+		return nil, request, topic, false
+	}
+
+	return bot, request, topic, true
+}
+
+func WebAppChallengeHandler(c *gin.Context) {
+	bot, _, topic, ok := webAppTopic(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"seed":       captcha.WebAppSeed(bot.Token, topic.ChallangeId),
+		"difficulty": captcha.WebAppDifficulty,
+	})
+}
+
+func WebAppVerifyHandler(c *gin.Context) {
+	bot, request, topic, ok := webAppTopic(c)
+	if !ok {
+		return
+	}
+
+	bot.bot.RLock()
+	defer bot.bot.RUnlock()
+
+	err := bot.topics.Lock(topic.UserId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer bot.topics.Unlock(topic.UserId)
+
+	// Reload, the challange may be changed before the lock is held
+	user_id, challangeId := topic.UserId, topic.ChallangeId
+	topic = model.Topic{}
+	err = DB().Where("bot_id", bot.Self.ID).Where("user_id", user_id).Find(&topic).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if topic.Verification != model.VerificationNotCompleted || topic.ChallangeId != challangeId {
+		c.JSON(http.StatusConflict, gin.H{"error": "challange changed"})
+		return
+	}
+
+	if !captcha.CheckProofOfWork(captcha.WebAppSeed(bot.Token, topic.ChallangeId), request.Nonce, captcha.WebAppDifficulty) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid proof of work"})
+		return
+	}
+
+	userChat := botapi.BaseChat{
+		ChatConfig: botapi.ChatConfig{
+			ChatID: topic.UserId,
+		},
+	}
+	bot.completeCaptcha(userChat, i18n.GetOrDefault(topic.LanguageCode), &topic)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
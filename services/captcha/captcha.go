@@ -0,0 +1,92 @@
+package captcha
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	mrand "math/rand/v2"
+	"strings"
+	"sync"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const (
+	TypeMath  = "math"
+	TypeImage = "image"
+)
+
+// Challenge is a kind of captcha, the answer is derived from the secret and the challange id so no state is kept.
+type Challenge interface {
+	// Generate creates the puzzle of the challange.
+	Generate(secret string, challangeId uint64) Puzzle
+	// Render renders the puzzle to a telegram message, text is the localized description.
+	Render(baseChat botapi.BaseChat, puzzle Puzzle, text string, entities []botapi.MessageEntity) botapi.Chattable
+	// Verify checks the callback data of the pressed answer button.
+	Verify(secret string, challangeId uint64, callbackData string) bool
+	// Check checks the typed answer, case and whitespaces are ignored.
+	Check(secret string, challangeId uint64, answer string) bool
+}
+
+type Puzzle struct {
+	Type        string
+	Problem     string // text problem
+	Image       []byte // PNG image problem
+	ReplyMarkup botapi.InlineKeyboardMarkup
+}
+
+var (
+	challenges   = make(map[string]Challenge)
+	challengesMu sync.RWMutex
+)
+
+func Register(name string, challenge Challenge) {
+	challengesMu.Lock()
+	defer challengesMu.Unlock()
+
+	challenges[name] = challenge
+}
+
+func Get(name string) (Challenge, bool) {
+	challengesMu.RLock()
+	defer challengesMu.RUnlock()
+
+	challenge, ok := challenges[name]
+	return challenge, ok
+}
+
+// challengeRand is seeded by the secret and the challange id, the same challange always generates the same puzzle.
+func challengeRand(secret string, kind string, challangeId uint64) *mrand.Rand {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(kind))
+	binary.Write(hash, binary.LittleEndian, challangeId)
+
+	var seed [32]byte
+	copy(seed[:], hash.Sum(nil))
+	return mrand.New(mrand.NewChaCha8(seed))
+}
+
+func normalizeAnswer(answer string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(answer), ""))
+}
+
+func checkAnswer(expected, answer string) bool {
+	return subtle.ConstantTimeCompare([]byte(normalizeAnswer(expected)), []byte(normalizeAnswer(answer))) == 1
+}
+
+// signCallbackData signs the answer button, value 0 is the correct answer.
+func signCallbackData(secret string, challangeId, value uint64) string {
+	hash := hmac.New(md5.New, []byte(secret))
+	binary.Write(hash, binary.LittleEndian, challangeId)
+	binary.Write(hash, binary.LittleEndian, value)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func checkCallbackData(secret string, challangeId uint64, callbackData string) bool {
+	signed := signCallbackData(secret, challangeId, 0)
+	return subtle.ConstantTimeCompare([]byte(signed), []byte(callbackData)) == 1
+}
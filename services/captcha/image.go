@@ -0,0 +1,204 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	mrand "math/rand/v2"
+	"slices"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const (
+	imageWidth   = 200
+	imageHeight  = 70
+	imageScale   = 5
+	imageLength  = 5
+	imageAnswers = 4
+)
+
+func init() {
+	Register(TypeImage, imageChallenge{})
+}
+
+// imageChallenge asks the user to pick the distorted text shown in a picture.
+type imageChallenge struct{}
+
+func randomText(r *mrand.Rand) string {
+	text := make([]byte, imageLength)
+	for i := range text {
+		text[i] = glyphAlphabet[r.IntN(len(glyphAlphabet))]
+	}
+	return string(text)
+}
+
+func (imageChallenge) Generate(secret string, challangeId uint64) Puzzle {
+	r := challengeRand(secret, TypeImage, challangeId)
+	answer := randomText(r)
+
+	texts := []string{answer}
+	for len(texts) < imageAnswers {
+		// Decoys differ from the answer in one or two characters
+		text := []byte(answer)
+		for range 1 + r.IntN(2) {
+			text[r.IntN(len(text))] = glyphAlphabet[r.IntN(len(glyphAlphabet))]
+		}
+
+		if slices.Contains(texts, string(text)) {
+			continue
+		}
+		texts = append(texts, string(text))
+	}
+
+	buttons := make([]botapi.InlineKeyboardButton, 0, len(texts))
+	for i, text := range texts {
+		buttons = append(buttons, botapi.NewInlineKeyboardButtonData(text, signCallbackData(secret, challangeId, uint64(i))))
+	}
+
+	r.Shuffle(len(buttons), func(i, j int) {
+		buttons[i], buttons[j] = buttons[j], buttons[i]
+	})
+
+	return Puzzle{
+		Type:        TypeImage,
+		Image:       drawText(r, answer),
+		ReplyMarkup: botapi.NewInlineKeyboardMarkup(buttons),
+	}
+}
+
+func (imageChallenge) Render(baseChat botapi.BaseChat, puzzle Puzzle, text string, entities []botapi.MessageEntity) botapi.Chattable {
+	if len(puzzle.ReplyMarkup.InlineKeyboard) != 0 {
+		baseChat.ReplyMarkup = puzzle.ReplyMarkup
+	}
+
+	return botapi.PhotoConfig{
+		BaseFile: botapi.BaseFile{
+			BaseChat: baseChat,
+			File: botapi.FileBytes{
+				Name:  "captcha.png",
+				Bytes: puzzle.Image,
+			},
+		},
+		Caption:         text,
+		CaptionEntities: entities,
+	}
+}
+
+func (imageChallenge) Verify(secret string, challangeId uint64, callbackData string) bool {
+	return checkCallbackData(secret, challangeId, callbackData)
+}
+
+func (imageChallenge) Check(secret string, challangeId uint64, answer string) bool {
+	r := challengeRand(secret, TypeImage, challangeId)
+	return checkAnswer(randomText(r), answer)
+}
+
+// drawText draws the text with waves, shears, noise lines and dots.
+func drawText(r *mrand.Rand, text string) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+
+	background := color.RGBA{uint8(220 + r.IntN(36)), uint8(220 + r.IntN(36)), uint8(220 + r.IntN(36)), 255}
+	for y := range imageHeight {
+		for x := range imageWidth {
+			img.SetRGBA(x, y, background)
+		}
+	}
+
+	randomColor := func(max int) color.RGBA {
+		return color.RGBA{uint8(r.IntN(max)), uint8(r.IntN(max)), uint8(r.IntN(max)), 255}
+	}
+
+	amplitude := 2 + r.Float64()*3
+	period := 8 + r.Float64()*8
+	phaseX, phaseY := r.Float64()*2*math.Pi, r.Float64()*2*math.Pi
+
+	for i := range len(text) {
+		glyph := glyphs[text[i]]
+		ink := randomColor(120)
+
+		originX := 12 + i*36 + r.IntN(6)
+		originY := 10 + r.IntN(imageHeight-glyphHeight*imageScale-16)
+		shear := (r.Float64() - 0.5) * 0.6
+
+		for gy, row := range glyph {
+			for gx := range glyphWidth {
+				if row[gx] != '#' {
+					continue
+				}
+
+				for py := range imageScale {
+					for px := range imageScale {
+						dy := float64(gy*imageScale + py)
+						x := float64(originX+gx*imageScale+px) + shear*(dy-glyphHeight*imageScale/2)
+						y := float64(originY) + dy
+
+						x += amplitude * math.Sin(y/period+phaseX)
+						y += amplitude * math.Sin(x/period+phaseY)
+
+						setPixel(img, int(x), int(y), ink)
+					}
+				}
+			}
+		}
+	}
+
+	for range 6 {
+		drawLine(img, r.IntN(imageWidth), r.IntN(imageHeight), r.IntN(imageWidth), r.IntN(imageHeight), randomColor(160))
+	}
+
+	for range 400 {
+		setPixel(img, r.IntN(imageWidth), r.IntN(imageHeight), randomColor(256))
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func setPixel(img *image.RGBA, x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+
+	img.SetRGBA(x, y, c)
+}
+
+// drawLine draws a line with the Bresenham's algorithm.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		setPixel(img, x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
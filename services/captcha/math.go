@@ -1,56 +1,75 @@
 package captcha
 
 import (
-	"crypto/hmac"
-	"crypto/md5"
-	"crypto/subtle"
-	"encoding/binary"
-	"encoding/hex"
 	"fmt"
+	mrand "math/rand/v2"
 	"slices"
 	"strconv"
 
 	botapi "github.com/OvyFlash/telegram-bot-api"
-	"gitlab.com/go-extension/rand"
 )
 
-func generateMathCallbackData(secret string, challangeId, value uint64) string {
-	hash := hmac.New(md5.New, []byte(secret))
-	binary.Write(hash, binary.LittleEndian, challangeId)
-	binary.Write(hash, binary.LittleEndian, value)
+func init() {
+	Register(TypeMath, mathChallenge{})
+}
+
+type mathChallenge struct{}
+
+func (mathChallenge) Generate(secret string, challangeId uint64) Puzzle {
+	problem, replyMarkup := NewMath(secret, challangeId)
+	return Puzzle{Type: TypeMath, Problem: problem, ReplyMarkup: replyMarkup}
+}
+
+func (mathChallenge) Render(baseChat botapi.BaseChat, puzzle Puzzle, text string, entities []botapi.MessageEntity) botapi.Chattable {
+	if len(puzzle.ReplyMarkup.InlineKeyboard) != 0 {
+		baseChat.ReplyMarkup = puzzle.ReplyMarkup
+	}
+
+	return botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	}
+}
+
+func (mathChallenge) Verify(secret string, challangeId uint64, callbackData string) bool {
+	return CheckMath(secret, challangeId, callbackData)
+}
 
-	return hex.EncodeToString(hash.Sum(nil))
+func (mathChallenge) Check(secret string, challangeId uint64, answer string) bool {
+	_, expected := mathProblem(challengeRand(secret, TypeMath, challangeId))
+	return checkAnswer(strconv.Itoa(expected), answer)
 }
 
 func CheckMath(secret string, challangeId uint64, callbackData string) bool {
-	signed := generateMathCallbackData(secret, challangeId, 0)
-	return subtle.ConstantTimeCompare([]byte(signed), []byte(callbackData)) == 1
+	return checkCallbackData(secret, challangeId, callbackData)
 }
 
-func NewMath(secret string, challangeId uint64) (string, botapi.InlineKeyboardMarkup) {
-	add := rand.Crypto.IntN(10) < 5
-	number1 := rand.Crypto.IntN(100)
-	number2 := rand.Crypto.IntN(100)
+func mathProblem(r *mrand.Rand) (string, int) {
+	add := r.IntN(10) < 5
+	number1 := r.IntN(100)
+	number2 := r.IntN(100)
 
 	if number1 < number2 {
 		number1, number2 = number2, number1
 	}
 
-	var problem string
-	var answer int
 	if add {
-		problem = fmt.Sprintf("%d + %d = ?", number1, number2)
-		answer = number1 + number2
-	} else {
-		problem = fmt.Sprintf("%d - %d = ?", number1, number2)
-		answer = number1 - number2
+		return fmt.Sprintf("%d + %d = ?", number1, number2), number1 + number2
 	}
 
+	return fmt.Sprintf("%d - %d = ?", number1, number2), number1 - number2
+}
+
+func NewMath(secret string, challangeId uint64) (string, botapi.InlineKeyboardMarkup) {
+	r := challengeRand(secret, TypeMath, challangeId)
+	problem, answer := mathProblem(r)
+
 	buttons := make([]botapi.InlineKeyboardButton, 0, 4)
-	buttons = append(buttons, botapi.NewInlineKeyboardButtonData(strconv.Itoa(answer), generateMathCallbackData(secret, challangeId, 0)))
+	buttons = append(buttons, botapi.NewInlineKeyboardButtonData(strconv.Itoa(answer), signCallbackData(secret, challangeId, 0)))
 
 	for len(buttons) < cap(buttons) {
-		number := rand.Crypto.IntN(answer + 100)
+		number := r.IntN(answer + 100)
 		text := strconv.Itoa(number)
 		if slices.ContainsFunc(buttons, func(button botapi.InlineKeyboardButton) bool {
 			return button.Text == text
@@ -59,10 +78,10 @@ func NewMath(secret string, challangeId uint64) (string, botapi.InlineKeyboardMa
 		}
 
 		number++ // make sure it not zero
-		buttons = append(buttons, botapi.NewInlineKeyboardButtonData(text, generateMathCallbackData(secret, challangeId, uint64(number))))
+		buttons = append(buttons, botapi.NewInlineKeyboardButtonData(text, signCallbackData(secret, challangeId, uint64(number))))
 	}
 
-	rand.Crypto.Shuffle(len(buttons), func(i, j int) {
+	r.Shuffle(len(buttons), func(i, j int) {
 		buttons[i], buttons[j] = buttons[j], buttons[i]
 	})
 	return problem, botapi.NewInlineKeyboardMarkup(buttons)
@@ -0,0 +1,152 @@
+package captcha
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+const (
+	TypeWebApp = "webapp"
+
+	WebAppDifficulty = 16 // leading zero bits of the proof-of-work
+	webAppMaxAge     = 24 * time.Hour
+)
+
+var ErrInvalidInitData = errors.New("invalid init data")
+
+// WebAppPage solves the proof-of-work puzzle in the Mini App.
+//
+//go:embed webapp.html
+var WebAppPage []byte
+
+func init() {
+	Register(TypeWebApp, webAppChallenge{})
+}
+
+// webAppChallenge asks the user to open a Mini App which solves a proof-of-work puzzle.
+// The answer is posted to the web server, so the callback and typed answers are never accepted.
+type webAppChallenge struct{}
+
+func (webAppChallenge) Generate(secret string, challangeId uint64) Puzzle {
+	return Puzzle{Type: TypeWebApp}
+}
+
+func (webAppChallenge) Render(baseChat botapi.BaseChat, puzzle Puzzle, text string, entities []botapi.MessageEntity) botapi.Chattable {
+	if len(puzzle.ReplyMarkup.InlineKeyboard) != 0 {
+		baseChat.ReplyMarkup = puzzle.ReplyMarkup
+	}
+
+	return botapi.MessageConfig{
+		BaseChat: baseChat,
+		Text:     text,
+		Entities: entities,
+	}
+}
+
+func (webAppChallenge) Verify(secret string, challangeId uint64, callbackData string) bool {
+	return false
+}
+
+func (webAppChallenge) Check(secret string, challangeId uint64, answer string) bool {
+	return false
+}
+
+// WebAppButton opens the Mini App of the challange.
+func WebAppButton(text string, url string) botapi.InlineKeyboardMarkup {
+	return botapi.NewInlineKeyboardMarkup([]botapi.InlineKeyboardButton{
+		{Text: text, WebApp: &botapi.WebAppInfo{URL: url}},
+	})
+}
+
+// WebAppSeed is the proof-of-work seed of the challange.
+func WebAppSeed(secret string, challangeId uint64) string {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(TypeWebApp))
+	binary.Write(hash, binary.LittleEndian, challangeId)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CheckProofOfWork checks SHA256(seed + nonce) has enough leading zero bits.
+func CheckProofOfWork(seed string, nonce string, difficulty int) bool {
+	if nonce == "" || len(nonce) > 32 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(seed + nonce))
+
+	var zeros int
+	for _, b := range sum {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+
+	return zeros >= difficulty
+}
+
+// ValidateInitData validates the init data of a Mini App signed by the bot token, it returns the id of the user.
+func ValidateInitData(token string, initData string) (int64, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return 0, ErrInvalidInitData
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return 0, ErrInvalidInitData
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+values.Get(key))
+	}
+
+	secretKey := hmac.New(sha256.New, []byte("WebAppData"))
+	secretKey.Write([]byte(token))
+
+	signature := hmac.New(sha256.New, secretKey.Sum(nil))
+	signature.Write([]byte(strings.Join(lines, "\n")))
+
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(signature.Sum(nil))), []byte(hash)) != 1 {
+		return 0, ErrInvalidInitData
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil || time.Since(time.Unix(authDate, 0)) > webAppMaxAge {
+		return 0, ErrInvalidInitData
+	}
+
+	var user struct {
+		Id int64 `json:"id"`
+	}
+	err = json.Unmarshal([]byte(values.Get("user")), &user)
+	if err != nil || user.Id == 0 {
+		return 0, ErrInvalidInitData
+	}
+
+	return user.Id, nil
+}
@@ -0,0 +1,146 @@
+package captcha
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "123456:test-token"
+
+func signInitData(token string, values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+values.Get(key))
+	}
+
+	secretKey := hmac.New(sha256.New, []byte("WebAppData"))
+	secretKey.Write([]byte(token))
+
+	signature := hmac.New(sha256.New, secretKey.Sum(nil))
+	signature.Write([]byte(strings.Join(lines, "\n")))
+
+	return hex.EncodeToString(signature.Sum(nil))
+}
+
+func initData(token string, authDate time.Time, user string) url.Values {
+	values := url.Values{
+		"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		"query_id":  {"AAHdF6IQAAAAAN0XohDhrOrc"},
+		"user":      {user},
+	}
+	values.Set("hash", signInitData(token, values))
+	return values
+}
+
+func TestValidateInitData(t *testing.T) {
+	now := time.Now()
+
+	tampered := initData(testToken, now, `{"id":42}`)
+	tampered.Set("user", `{"id":43}`)
+
+	badHash := initData(testToken, now, `{"id":42}`)
+	hash := []byte(badHash.Get("hash"))
+	hash[0] ^= 1
+	badHash.Set("hash", string(hash))
+
+	tests := []struct {
+		name     string
+		token    string
+		initData string
+		want     int64
+		wantErr  bool
+	}{
+		{"valid signature", testToken, initData(testToken, now, `{"id":42}`).Encode(), 42, false},
+		{"tampered field", testToken, tampered.Encode(), 0, true},
+		{"tampered hash", testToken, badHash.Encode(), 0, true},
+		{"other bot", "654321:other-token", initData(testToken, now, `{"id":42}`).Encode(), 0, true},
+		{"expired auth_date", testToken, initData(testToken, now.Add(-webAppMaxAge-time.Minute), `{"id":42}`).Encode(), 0, true},
+		{"missing hash", testToken, "auth_date=1&user=%7B%22id%22%3A42%7D", 0, true},
+		{"missing user", testToken, initData(testToken, now, `{}`).Encode(), 0, true},
+		{"malformed query", testToken, "%zz", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ValidateInitData(test.token, test.initData)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ValidateInitData() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Fatalf("ValidateInitData() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func leadingZeros(seed string, nonce string) int {
+	sum := sha256.Sum256([]byte(seed + nonce))
+
+	var zeros int
+	for _, b := range sum {
+		if b != 0 {
+			for b&0x80 == 0 {
+				zeros++
+				b <<= 1
+			}
+			break
+		}
+		zeros += 8
+	}
+
+	return zeros
+}
+
+// findNonce returns the first nonce whose hash has exactly the leading zero bits wanted.
+func findNonce(t *testing.T, seed string, zeros int) string {
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		if leadingZeros(seed, nonce) == zeros {
+			return nonce
+		}
+	}
+
+	t.Fatalf("no nonce with %d leading zero bits", zeros)
+	return ""
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	const difficulty = 12
+	seed := WebAppSeed("secret", 1)
+
+	tests := []struct {
+		name  string
+		seed  string
+		nonce string
+		want  bool
+	}{
+		{"exact difficulty", seed, findNonce(t, seed, difficulty), true},
+		{"above difficulty", seed, findNonce(t, seed, difficulty+2), true},
+		{"insufficient difficulty", seed, findNonce(t, seed, difficulty-1), false},
+		{"zero difficulty", seed, findNonce(t, seed, 0), false},
+		{"other challange", WebAppSeed("secret", 2), findNonce(t, seed, difficulty), false},
+		{"empty nonce", seed, "", false},
+		{"long nonce", seed, strings.Repeat("0", 33), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CheckProofOfWork(test.seed, test.nonce, difficulty); got != test.want {
+				t.Fatalf("CheckProofOfWork(%q, %q, %d) = %v, want %v", test.seed, test.nonce, difficulty, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gitlab.com/go-extension/rand"
+)
+
+var (
+	enabled bool
+	nodeId  string
+	leader  atomic.Bool
+)
+
+// Start joins the cluster, the node competes with other replicas for the leadership.
+func Start(id string) {
+	if id == "" {
+		hostname, _ := os.Hostname()
+		id = fmt.Sprintf("%s-%d-%x", hostname, os.Getpid(), rand.Crypto.Uint32())
+	}
+
+	enabled = true
+	nodeId = id
+
+	go elect()
+	clog.Infof("[Cluster] Node %s started", nodeId)
+}
+
+func Enabled() bool {
+	return enabled
+}
+
+func NodeId() string {
+	return nodeId
+}
+
+// IsLeader reports whether the node should run the singleton jobs, it is always true out of cluster mode.
+func IsLeader() bool {
+	return !enabled || leader.Load()
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	leaderLockName = "leader"
+
+	leaseTTL   = 30 * time.Second
+	leaseRenew = 10 * time.Second
+)
+
+func elect() {
+	for {
+		isLeader := renewLease()
+		if leader.Swap(isLeader) != isLeader {
+			if isLeader {
+				clog.Infof("[Cluster] Node %s is elected as leader", nodeId)
+			} else {
+				clog.Infof("[Cluster] Node %s lost the leadership", nodeId)
+			}
+		}
+
+		time.Sleep(leaseRenew)
+	}
+}
+
+func renewLease() bool {
+	now := time.Now()
+
+	result := DB().Model(model.Lock{}).Where("name", leaderLockName).Where(DB().Where("owner", nodeId).Or(clause.Lt{Column: "expires_at", Value: now.Unix()})).Updates(map[string]any{
+		"owner":      nodeId,
+		"expires_at": now.Add(leaseTTL).Unix(),
+	})
+	if result.Error != nil {
+		clog.Errorf("[Cluster] failed to renew lease, error: %s", result.Error)
+		return false
+	}
+
+	if result.RowsAffected > 0 {
+		return true
+	}
+
+	err := DB().Create(&model.Lock{
+		Name:      leaderLockName,
+		Owner:     nodeId,
+		ExpiresAt: now.Add(leaseTTL).Unix(),
+	}).Error
+	return err == nil
+}
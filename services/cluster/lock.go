@@ -0,0 +1,159 @@
+package cluster
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gitlab.com/go-extension/rand"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	lockTimeout    = 30 * time.Second
+	lockTTL        = time.Minute
+	lockRenew      = 20 * time.Second
+	lockRetryDelay = 50 * time.Millisecond
+)
+
+var errLockTimeout = errors.New("lock timeout")
+
+// Lock acquires the distributed lock of key, the returned unlock must be called.
+// Advisory locks are used on Postgres and MySQL, a lock table is used elsewhere.
+func Lock(key string) (func(), error) {
+	switch DB().Dialector.Name() {
+	case "postgres":
+		return advisoryLock(lockId(key))
+	case "mysql":
+		return namedLock(fmt.Sprintf("topicgram:%x", lockId(key)))
+	default:
+		return tableLock(key)
+	}
+}
+
+func lockId(key string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return int64(hash.Sum64())
+}
+
+func conn(ctx context.Context) (*sql.Conn, error) {
+	db, err := DB().DB()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Conn(ctx)
+}
+
+func advisoryLock(id int64) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	c, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.ExecContext(ctx, "SELECT pg_advisory_lock($1)", id)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return func() {
+		c.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", id)
+		c.Close()
+	}, nil
+}
+
+func namedLock(name string) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout+time.Second)
+	defer cancel()
+
+	c, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired sql.NullInt64
+	err = c.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(lockTimeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	if acquired.Int64 != 1 {
+		c.Close()
+		return nil, errLockTimeout
+	}
+
+	return func() {
+		c.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		c.Close()
+	}, nil
+}
+
+func tableLock(key string) (func(), error) {
+	owner := fmt.Sprintf("%s:%x", nodeId, rand.Crypto.Uint64())
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		now := time.Now()
+
+		// Release the lock held by a crashed node
+		DB().Where("name", key).Where(clause.Lt{Column: "expires_at", Value: now.Unix()}).Delete(&model.Lock{})
+
+		err := DB().Create(&model.Lock{
+			Name:      key,
+			Owner:     owner,
+			ExpiresAt: now.Add(lockTTL).Unix(),
+		}).Error
+		if err == nil {
+			stop := make(chan struct{})
+			go renewLock(key, owner, stop)
+
+			return func() {
+				close(stop)
+				DB().Where("name", key).Where("owner", owner).Delete(&model.Lock{})
+			}, nil
+		}
+
+		if now.After(deadline) {
+			return nil, errLockTimeout
+		}
+
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// renewLock extends the lock until stopped, so a long critical section does not lose the lock to others.
+func renewLock(key, owner string, stop <-chan struct{}) {
+	ticker := time.NewTicker(lockRenew)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			result := DB().Model(model.Lock{}).Where("name", key).Where("owner", owner).Update("expires_at", now.Add(lockTTL).Unix())
+			if result.Error != nil {
+				clog.Errorf("[Cluster] failed to renew lock %s, error: %s", key, result.Error)
+				continue
+			}
+
+			// Taken over by others, nothing to renew
+			if result.RowsAffected == 0 {
+				clog.Errorf("[Cluster] lock %s is lost", key)
+				return
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package cron
 
 import (
+	"Topicgram/services/cluster"
+
 	"github.com/robfig/cron/v3"
 	"gitlab.com/CoiaPrant/clog"
 )
@@ -21,8 +23,15 @@ func Stop() {
 	clog.Info("[CronJob] Stop all cron jobs")
 }
 
+// AddCron adds a job, in cluster mode the job only runs on the leader node.
 func AddCron(spec string, cmd func()) (id cron.EntryID, err error) {
-	id, err = cronjob.AddFunc(spec, cmd)
+	id, err = cronjob.AddFunc(spec, func() {
+		if !cluster.IsLeader() {
+			return
+		}
+
+		cmd()
+	})
 	if err != nil {
 		clog.Errorf("[CronJob] failed to add job, spec: %s, error: %s", spec, err)
 		return
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/bots"
+	"Topicgram/services/cron"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	_, err := cron.AddCron("@every 1m", BanExpiry)
+	if err != nil {
+		clog.Fatalf("[CronJob] failed to add job, error: %s", err)
+		return
+	}
+}
+
+// BanExpiry lifts the timed bans which are expired.
+func BanExpiry() {
+	var topics []model.Topic
+	err := DB().Select("bot_id", "user_id").Where("is_ban", true).Not("ban_until", 0).Where(clause.Lte{Column: "ban_until", Value: time.Now().Unix()}).Find(&topics).Error
+	if err != nil {
+		clog.Errorf("[CronJob][Ban Expiry] failed to execute, error: %s", err)
+		return
+	}
+
+	var expired int
+	for _, topic := range topics {
+		bot, ok := bots.Get(topic.BotId)
+		if !ok {
+			continue
+		}
+
+		ok, err = bot.ExpireBan(topic.UserId)
+		if err != nil {
+			clog.Errorf("[CronJob][Ban Expiry] failed to unban user %d of bot %d, error: %s", topic.UserId, topic.BotId, err)
+			continue
+		}
+
+		if ok {
+			expired++
+		}
+	}
+
+	if expired > 0 {
+		clog.Successf("[CronJob][Ban Expiry] Execute completed, %d bans expired", expired)
+	}
+}
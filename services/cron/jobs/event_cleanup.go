@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/cron"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gorm.io/gorm/clause"
+)
+
+// FAILED_EVENT_RETENTION keeps the failed events for inspection.
+const FAILED_EVENT_RETENTION = 30 * 24 * time.Hour
+
+func init() {
+	_, err := cron.AddCron("0 0 * * *", EventCleanup)
+	if err != nil {
+		clog.Fatalf("[CronJob] failed to add job, error: %s", err)
+		return
+	}
+}
+
+func EventCleanup() {
+	err := DB().Model(model.Event{}).Where("status", model.EventDelivered).Delete(nil).Error
+	if err != nil {
+		clog.Errorf("[CronJob][Event Cleanup] failed to execute, error: %s", err)
+		return
+	}
+
+	err = DB().Model(model.Event{}).Where("status", model.EventFailed).Where(clause.Lte{Column: "created_at", Value: time.Now().Add(-FAILED_EVENT_RETENTION)}).Delete(nil).Error
+	if err != nil {
+		clog.Errorf("[CronJob][Event Cleanup] failed to execute, error: %s", err)
+		return
+	}
+
+	clog.Success("[CronJob][Event Cleanup] Execute completed")
+}
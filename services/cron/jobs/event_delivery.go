@@ -0,0 +1,16 @@
+package jobs
+
+import (
+	"Topicgram/services/cron"
+	"Topicgram/services/events"
+
+	"gitlab.com/CoiaPrant/clog"
+)
+
+func init() {
+	_, err := cron.AddCron("@every 10s", events.Deliver)
+	if err != nil {
+		clog.Fatalf("[CronJob] failed to add job, error: %s", err)
+		return
+	}
+}
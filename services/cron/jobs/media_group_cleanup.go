@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/cron"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	_, err := cron.AddCron("@hourly", MediaGroupCleanup)
+	if err != nil {
+		clog.Fatalf("[CronJob] failed to add job, error: %s", err)
+		return
+	}
+}
+
+// MediaGroupCleanup removes the albums staged in cluster mode.
+func MediaGroupCleanup() {
+	expired := clause.Lte{Column: "created_at", Value: time.Now().Add(-time.Hour)}
+
+	err := DB().Model(model.MediaGroupItem{}).Where(expired).Delete(nil).Error
+	if err != nil {
+		clog.Errorf("[CronJob][Media Group Cleanup] failed to execute, error: %s", err)
+		return
+	}
+
+	err = DB().Model(model.MediaGroup{}).Where(expired).Delete(nil).Error
+	if err != nil {
+		clog.Errorf("[CronJob][Media Group Cleanup] failed to execute, error: %s", err)
+		return
+	}
+
+	clog.Success("[CronJob][Media Group Cleanup] Execute completed")
+}
@@ -1,14 +1,10 @@
 package jobs
 
 import (
-	. "Topicgram/database"
-	"Topicgram/model"
 	"Topicgram/services/bots"
 	"Topicgram/services/cron"
-	"time"
 
 	"gitlab.com/CoiaPrant/clog"
-	"gorm.io/gorm/clause"
 )
 
 func init() {
@@ -20,13 +16,11 @@ func init() {
 }
 
 func VerificationCleanup() {
-	err := DB().Model(model.Topic{}).Where("verification", model.VerificationNotCompleted).Not("challange_sent", 0).Where(clause.Lte{Column: "challange_sent", Value: time.Now().Add(bots.CAPTCHA_DURATION).Unix()}).Updates(map[string]any{
-		"challange_id":   0,
-		"challange_sent": 0,
-	}).Error
-	if err != nil {
-		clog.Errorf("[CronJob][Verification Cleanup] failed to execute, error: %s", err)
-		return
+	for _, bot := range bots.All() {
+		err := bot.ExpireChallenges()
+		if err != nil {
+			clog.Errorf("[CronJob][Verification Cleanup] failed to expire challenges of bot %d, error: %s", bot.Self.ID, err)
+		}
 	}
 
 	clog.Success("[CronJob][Verification Cleanup] Execute completed")
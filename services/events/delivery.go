@@ -0,0 +1,140 @@
+package events
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"Topicgram/services/cluster"
+	"Topicgram/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	deliveryBatchSize   = 100
+	deliveryMaxAttempts = 10
+
+	retryBaseDelay = 10 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+var delivering sync.Mutex
+
+// Deliver sends all due events in the outbox, in cluster mode only the leader node delivers.
+func Deliver() {
+	if !cluster.IsLeader() {
+		return
+	}
+
+	if !delivering.TryLock() {
+		return
+	}
+	defer delivering.Unlock()
+
+	var outbox []model.Event
+	err := DB().Where("status", model.EventPending).Where(clause.Lte{Column: "next_attempt", Value: time.Now().Unix()}).Order("id").Limit(deliveryBatchSize).Find(&outbox).Error
+	if err != nil {
+		clog.Errorf("[Events] failed to query outbox, error: %s", err)
+		return
+	}
+
+	// Endpoints are delivered concurrently, the events of an endpoint in order
+	queues := make(map[string][]*model.Event)
+	for i := range outbox {
+		event := &outbox[i]
+		queues[event.Endpoint] = append(queues[event.Endpoint], event)
+	}
+
+	var wg sync.WaitGroup
+	for _, queue := range queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for _, event := range queue {
+				// The endpoint is down, the rest is retried in later batches
+				if !deliver(event) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver sends the event, it reports whether the endpoint accepted it.
+func deliver(event *model.Event) bool {
+	endpoint, ok := getEndpoint(event.Endpoint)
+	if !ok {
+		event.Status = model.EventFailed
+		event.LastError = "endpoint removed"
+		DB().Save(event)
+		return true
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	status, _, err := utils.Curl("POST", endpoint.URL, []byte(event.Payload), map[string]string{
+		"Content-Type":          "application/json",
+		"User-Agent":            "Topicgram",
+		"X-Topicgram-Event":     event.Event,
+		"X-Topicgram-Delivery":  strconv.FormatInt(event.Id, 10),
+		"X-Topicgram-Timestamp": timestamp,
+		"X-Topicgram-Signature": "sha256=" + sign(endpoint.Secret, timestamp, event.Payload),
+	})
+
+	event.Attempts++
+	event.LastStatus = status
+
+	switch {
+	case err != nil:
+		event.LastError = err.Error()
+	case status < 200 || status > 299:
+		event.LastError = fmt.Sprintf("unexpected status %d", status)
+	default:
+		event.Status = model.EventDelivered
+		event.LastError = ""
+		event.DeliveredAt = time.Now().Unix()
+	}
+
+	if event.Status == model.EventPending {
+		if event.Attempts >= deliveryMaxAttempts {
+			event.Status = model.EventFailed
+			clog.Errorf("[Events] delivery %d to %s failed permanently, error: %s", event.Id, event.Endpoint, event.LastError)
+		} else {
+			event.NextAttempt = time.Now().Add(backoff(event.Attempts)).Unix()
+		}
+	}
+
+	err = DB().Save(event).Error
+	if err != nil {
+		clog.Errorf("[Events] failed to update delivery %d, error: %s", event.Id, err)
+	}
+
+	return event.Status == model.EventDelivered
+}
+
+func backoff(attempts int) time.Duration {
+	delay := retryBaseDelay << (attempts - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+
+	return delay
+}
+
+// sign returns the hex encoded HMAC-SHA256 of "timestamp.payload".
+func sign(secret, timestamp, payload string) string {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(timestamp))
+	hash.Write([]byte("."))
+	hash.Write([]byte(payload))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"gitlab.com/CoiaPrant/clog"
+)
+
+const (
+	TopicCreated    = "topic.created"
+	MessageReceived = "message.received"
+	MessageReplied  = "message.replied"
+	UserBanned      = "user.banned"
+	UserUnbanned    = "user.unbanned"
+	UserTerminated  = "user.terminated"
+	UserBlocked     = "user.blocked"
+	CaptchaPassed   = "captcha.passed"
+	CaptchaFailed   = "captcha.failed"
+)
+
+var endpoints []model.EventEndpoint
+
+type Payload struct {
+	Event     string `json:"event"`
+	BotId     int64  `json:"bot_id"`
+	Timestamp int64  `json:"timestamp"`
+	Data      any    `json:"data"`
+}
+
+func Load(eventEndpoints []model.EventEndpoint) {
+	endpoints = eventEndpoints
+}
+
+func getEndpoint(url string) (model.EventEndpoint, bool) {
+	index := slices.IndexFunc(endpoints, func(endpoint model.EventEndpoint) bool {
+		return endpoint.URL == url
+	})
+	if index < 0 {
+		return model.EventEndpoint{}, false
+	}
+
+	return endpoints[index], true
+}
+
+// Publish stores the event in the outbox for every subscribed endpoint, deliveries are retried until succeeded.
+func Publish(bot_id int64, event string, data any) {
+	if len(endpoints) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(Payload{
+		Event:     event,
+		BotId:     bot_id,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		clog.Errorf("[Events] failed to marshal event %s, error: %s", event, err)
+		return
+	}
+
+	var outbox []model.Event
+	for _, endpoint := range endpoints {
+		if len(endpoint.Events) > 0 && !slices.Contains(endpoint.Events, event) {
+			continue
+		}
+
+		outbox = append(outbox, model.Event{
+			BotId:    bot_id,
+			Endpoint: endpoint.URL,
+			Event:    event,
+			Payload:  string(payload),
+		})
+	}
+
+	if len(outbox) == 0 {
+		return
+	}
+
+	err = DB().Create(outbox).Error
+	if err != nil {
+		clog.Errorf("[Events] failed to save event %s, error: %s", event, err)
+		return
+	}
+
+	go Deliver()
+}
@@ -0,0 +1,95 @@
+package transcript
+
+import (
+	"Topicgram/model"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04:05 MST"
+
+func renderJSON(transcript *Transcript) ([]byte, error) {
+	return json.MarshalIndent(transcript, "", "    ")
+}
+
+func renderText(transcript *Transcript) ([]byte, error) {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Transcript of user %d (bot %d)\n", transcript.UserId, transcript.BotId)
+	fmt.Fprintf(&buf, "Generated at %s\n", transcript.GeneratedAt.Format(timeLayout))
+
+	for _, message := range transcript.Messages {
+		fmt.Fprintf(&buf, "\n[%s] %s (%s):\n", message.CreatedAt.Format(timeLayout), message.SenderName, message.Side)
+		writeText(&buf, message.Archive, "")
+
+		for _, edit := range message.Edits {
+			fmt.Fprintf(&buf, "  [edited %s]\n", edit.CreatedAt.Format(timeLayout))
+			writeText(&buf, edit, "  ")
+		}
+	}
+
+	return []byte(buf.String()), nil
+}
+
+func writeText(buf *strings.Builder, archive model.Archive, indent string) {
+	if archive.Content != "" {
+		for _, line := range strings.Split(archive.Content, "\n") {
+			buf.WriteString(indent + line + "\n")
+		}
+	}
+
+	if archive.MediaType != "" {
+		fmt.Fprintf(buf, "%s[%s: %s]\n", indent, archive.MediaType, archive.FileId)
+	}
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": func(t time.Time) string {
+		return t.Format(timeLayout)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript of user {{.UserId}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 0 auto; padding: 16px; background: #f4f4f5; }
+.message { margin: 8px 0; padding: 8px 12px; border-radius: 8px; background: #fff; }
+.admin { margin-left: 64px; background: #e7f3ff; }
+.user { margin-right: 64px; }
+.note { margin-left: 64px; background: #fff8e1; }
+.meta { font-size: 12px; color: #71717a; }
+.content { white-space: pre-wrap; word-break: break-word; }
+.media { font-family: monospace; font-size: 12px; color: #52525b; }
+.edit { margin-top: 6px; padding-left: 8px; border-left: 2px solid #d4d4d8; }
+</style>
+</head>
+<body>
+<h1>Transcript of user {{.UserId}}</h1>
+<p class="meta">Bot {{.BotId}}, generated at {{time .GeneratedAt}}</p>
+{{range .Messages}}<div class="message {{.Side}}">
+<div class="meta">{{time .CreatedAt}} · {{.SenderName}} ({{.Side}})</div>
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{if .MediaType}}<div class="media">{{.MediaType}}: {{.FileId}}</div>{{end}}
+{{range .Edits}}<div class="edit">
+<div class="meta">edited at {{time .CreatedAt}}</div>
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{if .MediaType}}<div class="media">{{.MediaType}}: {{.FileId}}</div>{{end}}
+</div>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+func renderHTML(transcript *Transcript) ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, transcript)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
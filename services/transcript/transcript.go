@@ -0,0 +1,84 @@
+package transcript
+
+import (
+	. "Topicgram/database"
+	"Topicgram/model"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatHTML = "html"
+	FormatText = "text"
+)
+
+var ErrUnknownFormat = errors.New("unknown transcript format")
+
+type Transcript struct {
+	BotId       int64     `json:"bot_id"`
+	UserId      int64     `json:"user_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Messages    []Message `json:"messages"`
+}
+
+type Message struct {
+	model.Archive
+	Side  string          `json:"side"` // user, admin
+	Edits []model.Archive `json:"edits,omitempty"`
+}
+
+// Load collects the archived conversation of the user, edits are attached to the edited messages.
+func Load(bot_id, user_id int64) (*Transcript, error) {
+	var archives []model.Archive
+	err := DB().Where("bot_id", bot_id).Where("user_id", user_id).Order("id").Find(&archives).Error
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := &Transcript{
+		BotId:       bot_id,
+		UserId:      user_id,
+		GeneratedAt: time.Now(),
+		Messages:    make([]Message, 0, len(archives)),
+	}
+
+	index := make(map[int64]int, len(archives))
+	for _, archive := range archives {
+		if archive.EditOf != 0 {
+			if i, ok := index[archive.EditOf]; ok {
+				transcript.Messages[i].Edits = append(transcript.Messages[i].Edits, archive)
+			}
+			continue
+		}
+
+		index[archive.Id] = len(transcript.Messages)
+		transcript.Messages = append(transcript.Messages, Message{Archive: archive, Side: archive.Direction.String()})
+	}
+
+	return transcript, nil
+}
+
+// Render renders the transcript as json, html or text.
+func (transcript *Transcript) Render(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return renderJSON(transcript)
+	case FormatHTML:
+		return renderHTML(transcript)
+	case FormatText:
+		return renderText(transcript)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func (transcript *Transcript) FileName(format string) string {
+	ext := format
+	if format == FormatText {
+		ext = "txt"
+	}
+
+	return fmt.Sprintf("transcript-%d-%d.%s", transcript.BotId, transcript.UserId, ext)
+}
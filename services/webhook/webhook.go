@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"Topicgram/services/api"
 	"Topicgram/services/bots"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,13 @@ func NewEngine() (router *gin.Engine) {
 
 	router.Use(gin.Recovery())
 
-	router.POST("/topicgram/webhook", bots.HookHandler)
+	router.POST("/topicgram/webhook/:botId", bots.HookHandler)
+	router.POST("/topicgram/manager", bots.ManagerHookHandler)
+
+	router.GET("/topicgram/captcha/:botId", bots.WebAppPageHandler)
+	router.POST("/topicgram/captcha/:botId/challenge", bots.WebAppChallengeHandler)
+	router.POST("/topicgram/captcha/:botId/verify", bots.WebAppVerifyHandler)
+
+	api.Register(router)
 	return
 }
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a duration like 30m, 12h, 7d or 2w, it also accepts the format of time.ParseDuration.
+func ParseDuration(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		number, ok := strings.CutSuffix(s, suffix)
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+
+		return time.Duration(n) * unit, true
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil || duration <= 0 {
+		return 0, false
+	}
+
+	return duration, true
+}
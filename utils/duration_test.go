@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"30m", 30 * time.Minute, true},
+		{"12h", 12 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"", 0, false},
+		{"d", 0, false},
+		{"0d", 0, false},
+		{"-1d", 0, false},
+		{"1.5d", 0, false},
+		{"0s", 0, false},
+		{"-5m", 0, false},
+		{"spam", 0, false},
+		{"10", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseDuration(test.input)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v, %v", test.input, got, ok, test.want, test.wantOk)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"regexp"
+	"unicode/utf16"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// UTF16Len returns the length of s in UTF-16 code units, the unit of the offsets of message entities.
+func UTF16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// ReplacePlaceholders replaces the placeholders like {name} with the values, the entities are shifted to keep the formatting.
+// Unknown placeholders are kept as is.
+func ReplacePlaceholders(text string, entities []botapi.MessageEntity, values map[string]string) (string, []botapi.MessageEntity) {
+	matches := placeholderRegexp.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text, entities
+	}
+
+	entities = append([]botapi.MessageEntity(nil), entities...)
+
+	// Replace from the end, the offsets before the placeholder are not changed
+	for i := len(matches) - 1; i >= 0; i-- {
+		start, end := matches[i][0], matches[i][1]
+
+		value, ok := values[text[start+1:end-1]]
+		if !ok {
+			continue
+		}
+
+		offset := UTF16Len(text[:start])
+		length := UTF16Len(text[start:end])
+		delta := UTF16Len(value) - length
+
+		for j := range entities {
+			entity := &entities[j]
+			switch {
+			case offset >= entity.Offset+entity.Length:
+				// The placeholder is after the entity
+			case offset+length <= entity.Offset:
+				entity.Offset += delta
+			default:
+				entity.Length = max(entity.Length+delta, 0)
+			}
+		}
+
+		text = text[:start] + value + text[end:]
+	}
+
+	return text, entities
+}
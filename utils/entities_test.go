@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	botapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+func TestUTF16Len(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"你好", 2},
+		{"😀", 2},
+		{"a😀b", 4},
+		{"👨‍👩‍👧", 8},
+	}
+
+	for _, test := range tests {
+		if got := UTF16Len(test.input); got != test.want {
+			t.Errorf("UTF16Len(%q) = %d, want %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestReplacePlaceholders(t *testing.T) {
+	bold := func(offset, length int) botapi.MessageEntity {
+		return botapi.MessageEntity{Type: "bold", Offset: offset, Length: length}
+	}
+
+	values := map[string]string{
+		"name":  "张三",
+		"emoji": "😀😀",
+		"empty": "",
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		entities     []botapi.MessageEntity
+		wantText     string
+		wantEntities []botapi.MessageEntity
+	}{
+		{
+			name:         "no placeholder",
+			text:         "Hello",
+			entities:     []botapi.MessageEntity{bold(0, 5)},
+			wantText:     "Hello",
+			wantEntities: []botapi.MessageEntity{bold(0, 5)},
+		},
+		{
+			name:         "entity before",
+			text:         "Hi {name}",
+			entities:     []botapi.MessageEntity{bold(0, 2)},
+			wantText:     "Hi 张三",
+			wantEntities: []botapi.MessageEntity{bold(0, 2)},
+		},
+		{
+			name:         "entity after multi-byte value",
+			text:         "Hi {name}, welcome",
+			entities:     []botapi.MessageEntity{bold(11, 7)},
+			wantText:     "Hi 张三, welcome",
+			wantEntities: []botapi.MessageEntity{bold(7, 7)},
+		},
+		{
+			name:         "entity after surrogate pair value",
+			text:         "{emoji} done",
+			entities:     []botapi.MessageEntity{bold(8, 4)},
+			wantText:     "😀😀 done",
+			wantEntities: []botapi.MessageEntity{bold(5, 4)},
+		},
+		{
+			name:         "entity around placeholder",
+			text:         "Hi {name}!",
+			entities:     []botapi.MessageEntity{bold(0, 10)},
+			wantText:     "Hi 张三!",
+			wantEntities: []botapi.MessageEntity{bold(0, 6)},
+		},
+		{
+			name:         "multi-byte text before placeholder",
+			text:         "你好😀 {name} 再见",
+			entities:     []botapi.MessageEntity{bold(0, 2), bold(12, 2)},
+			wantText:     "你好😀 张三 再见",
+			wantEntities: []botapi.MessageEntity{bold(0, 2), bold(8, 2)},
+		},
+		{
+			name:         "several placeholders",
+			text:         "{name} {emoji} {name}",
+			entities:     []botapi.MessageEntity{bold(7, 7), bold(15, 6)},
+			wantText:     "张三 😀😀 张三",
+			wantEntities: []botapi.MessageEntity{bold(3, 4), bold(8, 2)},
+		},
+		{
+			name:         "empty value",
+			text:         "a{empty}b",
+			entities:     []botapi.MessageEntity{bold(1, 7), bold(8, 1)},
+			wantText:     "ab",
+			wantEntities: []botapi.MessageEntity{bold(1, 0), bold(1, 1)},
+		},
+		{
+			name:         "unknown placeholder",
+			text:         "{unknown} {name}",
+			entities:     []botapi.MessageEntity{bold(0, 9)},
+			wantText:     "{unknown} 张三",
+			wantEntities: []botapi.MessageEntity{bold(0, 9)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entities := append([]botapi.MessageEntity(nil), test.entities...)
+
+			text, got := ReplacePlaceholders(test.text, entities, values)
+			if text != test.wantText {
+				t.Errorf("text = %q, want %q", text, test.wantText)
+			}
+			if !reflect.DeepEqual(got, test.wantEntities) {
+				t.Errorf("entities = %+v, want %+v", got, test.wantEntities)
+			}
+			if !reflect.DeepEqual(entities, test.entities) {
+				t.Errorf("the entities passed in are modified: %+v", entities)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "fmt"
+
+// MessageLink returns the link of a message in a forum topic of a supergroup.
+func MessageLink(chat_id int64, thread_id, message_id int) string {
+	// Supergroup ids are -100 prefixed
+	internal_id := -chat_id - 1000000000000
+	if thread_id == 0 {
+		return fmt.Sprintf("https://t.me/c/%d/%d", internal_id, message_id)
+	}
+
+	return fmt.Sprintf("https://t.me/c/%d/%d/%d", internal_id, thread_id, message_id)
+}